@@ -0,0 +1,10 @@
+package startup
+
+import "github.com/pygzfei/gin-cache/internal"
+
+// CircuitBreaker wrap the driver of cache in a circuit breaker, configure it with the returned value
+func CircuitBreaker(cache *internal.CacheHandler) *internal.CircuitBreaker {
+	breaker := internal.NewCircuitBreaker(cache.Cache)
+	cache.Cache = breaker
+	return breaker
+}
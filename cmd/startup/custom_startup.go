@@ -0,0 +1,26 @@
+package startup
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal"
+)
+
+// Cache driver without errors, a miss and a failure both load "", pass it to AdaptCache
+type Cache = internal.Cache
+
+// CacheV2 error-aware driver, Load returns define.ErrCacheMiss for a missing key
+type CacheV2 = internal.CacheV2
+
+// AdaptCache wrap a driver without errors for CustomCache
+func AdaptCache(c Cache) CacheV2 {
+	return internal.AdaptCache(c)
+}
+
+// CustomCache init with a driver of your own
+func CustomCache(driver CacheV2, onCacheHit ...func(c *gin.Context, cacheValue string)) (*internal.CacheHandler, error) {
+	if driver == nil {
+		return nil, errors.New("driver is required")
+	}
+	return internal.New(driver, nil, onCacheHit...), nil
+}
@@ -0,0 +1,42 @@
+package startup
+
+import (
+	"context"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// mapCache driver of the old interface
+type mapCache map[string]string
+
+func (m mapCache) Load(ctx context.Context, key string) string {
+	return m[key]
+}
+
+func (m mapCache) Set(ctx context.Context, key string, data string, timeout time.Duration) {
+	m[key] = data
+}
+
+func (m mapCache) DoEvict(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		delete(m, key)
+	}
+}
+
+func TestCustomCache(t *testing.T) {
+	_, err := CustomCache(nil)
+	assert.Error(t, err)
+
+	driver := mapCache{}
+	got, err := CustomCache(AdaptCache(driver))
+	assert.Nil(t, err)
+
+	got.Set(context.Background(), "anson:custom", "value", time.Minute)
+	assert.Equal(t, "value", driver["anson:custom"])
+	assert.Equal(t, "value", got.Load(context.Background(), "anson:custom"))
+
+	_, err = got.Cache.Load(context.Background(), "anson:missing")
+	assert.Equal(t, define.ErrCacheMiss, err)
+}
@@ -8,5 +8,5 @@ import (
 
 // MemCache NewMemoryCache init memory support
 func MemCache(onCacheHit ...func(c *gin.Context, cacheValue string)) (*internal.CacheHandler, error) {
-	return internal.New(memcache.NewMemoryHandler(), onCacheHit...), nil
+	return internal.New(memcache.NewMemoryHandler(), nil, onCacheHit...), nil
 }
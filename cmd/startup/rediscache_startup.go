@@ -14,5 +14,5 @@ func RedisCache(cacheTime time.Duration, options *redis.Options, onCacheHit ...f
 	if cacheTime <= 0 {
 		return nil, errors.New("CacheTime greater than 0")
 	}
-	return internal.New(rediscache.NewRedisHandler(redis.NewClient(options), cacheTime), onCacheHit...), nil
+	return internal.New(rediscache.NewRedisHandler(redis.NewClient(options), cacheTime), nil, onCacheHit...), nil
 }
@@ -0,0 +1,8 @@
+package startup
+
+import "github.com/pygzfei/gin-cache/internal"
+
+// LoadRuleFile read route rules of a yaml or json file, pass it to CacheHandler.FileMiddleware
+func LoadRuleFile(path string) (*internal.RuleFile, error) {
+	return internal.LoadRuleFile(path)
+}
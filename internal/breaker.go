@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"context"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultBreakerCooldown  = time.Second * 10
+)
+
+// CircuitBreaker decorate a driver, consecutive failures open it and the driver is not called until the cooldown passed.
+// misses and requests cancelled by the client are not failures
+type CircuitBreaker struct {
+	FailureThreshold int                         // consecutive failures opening the breaker, default 5
+	Cooldown         time.Duration               // how long it stays open before a trial call, default 10s
+	OnStateChange    func(from, to BreakerState) // called outside the lock on every transition
+
+	cache    CacheV2
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial call is running
+}
+
+// NewCircuitBreaker wrap c, use the result as CacheHandler.Cache
+func NewCircuitBreaker(c CacheV2) *CircuitBreaker {
+	return &CircuitBreaker{cache: c}
+}
+
+// State current state, an open breaker past its cooldown is reported half-open
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown() {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Available the driver may be called, CacheHandler skips the cache otherwise
+func (b *CircuitBreaker) Available() bool {
+	return b.State() != BreakerOpen
+}
+
+// DefaultTimeout of the wrapped driver
+func (b *CircuitBreaker) DefaultTimeout() time.Duration {
+	return defaultTimeout(b.cache)
+}
+
+func (b *CircuitBreaker) Load(ctx context.Context, key string) (string, error) {
+	if !b.allow() {
+		return "", ErrCircuitOpen
+	}
+	data, err := b.cache.Load(ctx, key)
+	b.done(err)
+	return data, err
+}
+
+func (b *CircuitBreaker) Set(ctx context.Context, key string, data string, timeout time.Duration) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := b.cache.Set(ctx, key, data, timeout)
+	b.done(err)
+	return err
+}
+
+func (b *CircuitBreaker) LoadBytes(ctx context.Context, key string) ([]byte, error) {
+	if !b.allow() {
+		return nil, ErrCircuitOpen
+	}
+	data, err := loadBytes(ctx, b.cache, key)
+	b.done(err)
+	return data, err
+}
+
+func (b *CircuitBreaker) SetBytes(ctx context.Context, key string, data []byte, timeout time.Duration) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := setBytes(ctx, b.cache, key, data, timeout)
+	b.done(err)
+	return err
+}
+
+func (b *CircuitBreaker) DoEvict(ctx context.Context, keys []string) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := b.cache.DoEvict(ctx, keys)
+	b.done(err)
+	return err
+}
+
+func (b *CircuitBreaker) Tag(ctx context.Context, key string, tags []string, timeout time.Duration) error {
+	driver, ok := b.cache.(TagCache)
+	if !ok {
+		return ErrTagsUnsupported
+	}
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := driver.Tag(ctx, key, tags, timeout)
+	b.done(err)
+	return err
+}
+
+func (b *CircuitBreaker) EvictTags(ctx context.Context, tags []string) error {
+	driver, ok := b.cache.(TagCache)
+	if !ok {
+		return ErrTagsUnsupported
+	}
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := driver.EvictTags(ctx, tags)
+	b.done(err)
+	return err
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown() {
+		b.state = BreakerHalfOpen
+	}
+	allowed := b.state == BreakerClosed
+	if b.state == BreakerHalfOpen && !b.trial {
+		b.trial, allowed = true, true
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+	return allowed
+}
+
+func (b *CircuitBreaker) done(err error) {
+	failed := err != nil && err != ErrCacheMiss && err != context.Canceled
+
+	b.mu.Lock()
+	from := b.state
+	switch {
+	case b.state == BreakerHalfOpen:
+		b.trial = false
+		if failed {
+			b.state, b.openedAt = BreakerOpen, time.Now()
+		} else {
+			b.state, b.failures = BreakerClosed, 0
+		}
+	case !failed:
+		b.failures = 0
+	case b.state == BreakerClosed:
+		if b.failures++; b.failures >= b.threshold() {
+			b.state, b.openedAt = BreakerOpen, time.Now()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+}
+
+func (b *CircuitBreaker) changed(from, to BreakerState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+func (b *CircuitBreaker) threshold() int {
+	if b.FailureThreshold > 0 {
+		return b.FailureThreshold
+	}
+	return defaultFailureThreshold
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown > 0 {
+		return b.Cooldown
+	}
+	return defaultBreakerCooldown
+}
@@ -3,18 +3,25 @@ package internal
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/entity"
 	"github.com/pygzfei/gin-cache/internal/utils"
 	"github.com/pygzfei/gin-cache/pkg"
 	. "github.com/pygzfei/gin-cache/pkg/define"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
 
 var bodyBytesKey = "bodyIO"
 
+// detachedKey marks a context copied to run the handler in the background
+var detachedKey = "gin-cache/detached"
+
+// Cache driver without errors, a miss and a failure both load "", wrap it with AdaptCache
 type Cache interface {
 	Load(ctx context.Context, key string) string
 	Set(ctx context.Context, key string, data string, timeout time.Duration)
@@ -22,24 +29,86 @@ type Cache interface {
 }
 
 type CacheHandler struct {
-	Cache      Cache
-	OnCacheHit CacheHitHook // 命中缓存钩子 优先级低
+	Cache        CacheV2
+	OnCacheHit   CacheHitHook // 命中缓存钩子 优先级低
+	CacheHeaders []string     // response headers stored with the body, default define.DefaultCacheHeaders
+	HTTPPolicy   *HTTPPolicy  // honor Cache-Control of request and response, nil ignores them
+
+	// OnCacheHitBytes binary-safe OnCacheHit, takes priority over it
+	OnCacheHitBytes CacheHitBytesHook
+
+	// OperationTimeout bound each Load, Set and DoEvict within the request context, 0 only the request context.
+	// a Load timed out is a miss
+	OperationTimeout time.Duration
+
+	// OnCacheError report failures of the driver, the request is served by the handler as on a miss
+	OnCacheError CacheErrorHook
+
+	// MaxRequestBodySize a larger request body is not read for params and the request is not cached, 0 no limit
+	MaxRequestBodySize int64
+	// MaxResponseBodySize a larger response is streamed to the client without being stored, 0 no limit
+	MaxResponseBodySize int
+	// KeyPrefix and Generation namespace every key passed to the driver as prefix:v{gen}:key, evict patterns too.
+	// bump Generation to invalidate every entry at once, both empty keep the keys as they are
+	KeyPrefix  string
+	Generation int
+
+	// CompressMinSize gzip stored bodies of at least this size, sent compressed to clients accepting gzip. 0 disables
+	CompressMinSize int
+
+	RefreshWorkers   int // background revalidation workers, default 4
+	RefreshQueueSize int // pending background revalidations, default 64, full queue skips the refresh
+
+	flights   flightGroup // coalesce concurrent misses of one key
+	refresher refresher   // stale-while-revalidate worker pool
 }
 
+// Load returns the cached response body of key
 func (cache *CacheHandler) Load(ctx context.Context, key string) string {
-	return cache.Cache.Load(ctx, key)
+	return string(cache.LoadBytes(ctx, key))
+}
+
+// LoadBytes returns the cached response body of key, nil on miss
+func (cache *CacheHandler) LoadBytes(ctx context.Context, key string) []byte {
+	response, _ := cache.loadCache(ctx, key)
+	if response == nil {
+		return nil
+	}
+	body, _ := decodedBody(response)
+	return body
 }
 
 func (cache *CacheHandler) Set(ctx context.Context, key string, data string, timeout time.Duration) {
-	cache.Cache.Set(ctx, key, data, timeout)
+	key = cache.namespaced(key)
+	cache.reportError(ctx, OpSet, []string{key}, cache.Cache.Set(ctx, key, data, timeout))
+}
+
+// SetBytes store data as is, the driver may keep the slice
+func (cache *CacheHandler) SetBytes(ctx context.Context, key string, data []byte, timeout time.Duration) {
+	key = cache.namespaced(key)
+	cache.reportError(ctx, OpSet, []string{key}, setBytes(ctx, cache.Cache, key, data, timeout))
 }
 
 func (cache *CacheHandler) DoEvict(ctx context.Context, keys []string) {
-	cache.Cache.DoEvict(ctx, keys)
+	keys = cache.namespacedKeys(keys)
+	cache.reportError(ctx, OpEvict, keys, cache.Cache.DoEvict(ctx, keys))
+}
+
+// New with an error-aware driver, wrap a Cache with AdaptCache
+func New(c CacheV2, policy *HTTPPolicy, onCacheHit ...func(c *gin.Context, cacheValue string)) *CacheHandler {
+	return &CacheHandler{Cache: c, OnCacheHit: onCacheHit, CacheHeaders: DefaultCacheHeaders, HTTPPolicy: policy}
 }
 
-func New(c Cache, onCacheHit ...func(c *gin.Context, cacheValue string)) *CacheHandler {
-	return &CacheHandler{c, onCacheHit}
+// cacheKey a Cacheable of the request with its generated key
+type cacheKey struct {
+	cacheable Cacheable
+	key       string
+}
+
+// cached a response loaded from the key
+type cached struct {
+	cacheKey
+	response *entity.Response
 }
 
 // Handler for startup
@@ -47,108 +116,512 @@ func (cache *CacheHandler) Handler(caching Caching, next gin.HandlerFunc) gin.Ha
 
 	return func(c *gin.Context) {
 
-		doCache := len(caching.Cacheable) > 0
-		doEvict := len(caching.Evict) > 0
-		ctx := context.Background()
+		// the backend is known to be down, e.g. an open CircuitBreaker
+		if !cache.available() {
+			next(c)
+			return
+		}
 
-		var key = ""
-		var cacheString = ""
+		// an oversized request streams through, only the evictions run with params of path and query
+		buffered := cache.bufferBody(c)
+		doCache := len(caching.Cacheable) > 0 && buffered
+		doEvict := len(caching.Evict) > 0 || len(caching.EvictRules) > 0
+		doPut := len(caching.Put) > 0 && buffered
+		ctx := c.Request.Context()
 
-		if c.Request.Body != nil {
-			body, err := ioutil.ReadAll(c.Request.Body)
-			if err != nil {
-				body = []byte("")
-			}
-			c.Set(bodyBytesKey, body)
-			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		var keys, puts []cacheKey
+		var hit, fallback *cached
+		// status of the handler itself, the fallback sent in place of a failure does not count
+		status := 0
+
+		if doEvict {
+			refreshBodyData(c)
+			cache.doCacheEvict(ctx, c, true, 0, evictRules(caching)...)
 		}
 
-		if doCache {
+		if doCache || doPut || evictByResponse(caching) {
 			// pointer 指向 writer, 重写 c.writer
 			c.Writer = &pkg.ResponseBodyWriter{
 				Body:           bytes.NewBufferString(""),
 				ResponseWriter: c.Writer,
+				Limit:          cache.MaxResponseBodySize,
+			}
+		}
+
+		if doCache {
+			keys = cache.getCacheKeys(caching.Cacheable, c)
+			// a put always runs the handler
+			if !doPut && !cache.bypassLookup(c) {
+				hit, fallback = cache.lookup(ctx, c, keys, next)
 			}
+		}
+		if doPut {
+			puts = cache.getCacheKeys(putCacheables(caching.Put), c)
+		}
 
-			key = cache.getCacheKey(caching.Cacheable[0], c)
-			if key != "" {
-				cacheString = cache.loadCache(ctx, key)
+		// only one request per key runs the handler, the others wait for its response
+		var miss *flight
+		if hit == nil && !doPut && len(keys) > 0 && keys[0].cacheable.WaitTimeout >= 0 {
+			f, leader := cache.flights.join(keys[0].key)
+			if leader {
+				miss = f
+				defer cache.flights.finish(keys[0].key, f)
+			} else if response := f.wait(c.Request.Context(), keys[0].cacheable.WaitTimeout); response != nil {
+				hit = &cached{cacheKey: keys[0], response: response}
 			}
 		}
 
-		if cacheString == "" {
+		if hit == nil {
 
 			refreshBodyData(c)
 
-			next(c)
+			if fallback == nil {
+				next(c)
+			} else {
+				var served bool
+				if status, served = cache.invokeWithFallback(c, fallback, next); served {
+					hit = fallback
+				}
+			}
 
 			refreshBodyData(c)
 
 		} else {
-			cache.doCacheHit(c, caching, cacheString)
+			cache.doCacheHit(c, hit.cacheable, hit.response)
 		}
 		if doEvict {
 			refreshBodyData(c)
-			cache.doCacheEvict(ctx, c, caching.Evict...)
+			if status == 0 {
+				status = c.Writer.Status()
+			}
+			cache.doCacheEvict(ctx, c, false, status, evictRules(caching)...)
 		}
-		if doCache {
-			if cacheString = cache.loadCache(ctx, key); cacheString == "" {
-				s := c.Writer.(*pkg.ResponseBodyWriter).Body.String()
-				cache.setCache(ctx, key, s, caching.Cacheable[0].CacheTime)
+
+		if hit == nil {
+			// write the response under every key, each with its own cache time
+			for i, k := range append(keys, puts...) {
+				captured := cache.captureResponse(c, k.cacheable)
+				if cache.shouldCache(c, k.cacheable, captured) {
+					stored := cache.setCache(ctx, c, k.key, captured, k.cacheable)
+					if i == 0 && miss != nil {
+						// the waiters are served like any hit, with its validators
+						miss.response = stored
+					}
+				}
 			}
+		} else if _, err := cache.loadCache(ctx, hit.key); err == ErrCacheMiss {
+			// the evict of this request removed the hit
+			cache.setCache(ctx, c, hit.key, hit.response, hit.cacheable)
 		}
 
 	}
 }
 
-func (cache *CacheHandler) getCacheKey(cacheable Cacheable, c *gin.Context) string {
+// getCacheKeys keys of every Cacheable in order, empty keys are skipped
+func (cache *CacheHandler) getCacheKeys(cacheables []Cacheable, c *gin.Context) []cacheKey {
+	keys := make([]cacheKey, 0, len(cacheables))
 	params := utils.ParameterParser(c)
-	return strings.ToLower(cacheable.GenKey(params))
+	vary := make(map[string]bool)
+	for _, cacheable := range cacheables {
+		if cacheable.Condition != nil && !cacheable.Condition(params, c) {
+			continue
+		}
+		for _, name := range cacheable.Vary {
+			if name = http.CanonicalHeaderKey(name); !vary[name] {
+				vary[name] = true
+				c.Writer.Header().Add("Vary", name)
+			}
+		}
+		if key := cache.getCacheKey(cacheable, params, c); key != "" {
+			keys = append(keys, cacheKey{cacheable: cacheable, key: key})
+		}
+	}
+	return keys
 }
 
-func (cache *CacheHandler) loadCache(ctx context.Context, key string) string {
-	return cache.Cache.Load(ctx, key)
+// lookup try the keys in order, the first fresh or revalidating entry is the hit,
+// the first entry within its StaleIfError window is the fallback
+func (cache *CacheHandler) lookup(ctx context.Context, c *gin.Context, keys []cacheKey, next gin.HandlerFunc) (hit, fallback *cached) {
+	now := time.Now()
+	for _, k := range keys {
+		response, _ := cache.loadCache(ctx, k.key)
+		if response == nil {
+			continue
+		}
+		if response.IsFresh(now) {
+			return &cached{cacheKey: k, response: response}, fallback
+		}
+
+		// stale entry, served within the revalidate window while a worker refreshes it
+		staleness := response.Staleness(now)
+		if staleness <= k.cacheable.StaleWhileRevalidate {
+			cache.revalidate(c, k.key, k.cacheable, next)
+			return &cached{cacheKey: k, response: response}, fallback
+		}
+		if fallback == nil && staleness <= k.cacheable.StaleIfError {
+			fallback = &cached{cacheKey: k, response: response}
+		}
+	}
+	return nil, fallback
 }
 
-func (cache *CacheHandler) setCache(ctx context.Context, key string, data string, timeout time.Duration) {
-	cache.Cache.Set(ctx, key, data, timeout)
+// invokeWithFallback hold the response of next, send the fallback instead when it failed.
+// a response beyond MaxResponseBodySize gives up the fallback and streams to the client.
+// returns the status of next and true when the fallback was served
+func (cache *CacheHandler) invokeWithFallback(c *gin.Context, fallback *cached, next gin.HandlerFunc) (int, bool) {
+	writer := c.Writer
+	buffered := pkg.NewBufferedResponseWriter()
+	buffered.Limit = cache.MaxResponseBodySize
+	buffered.Overflow = writer
+	for name, values := range writer.Header() {
+		buffered.Header()[name] = append([]string(nil), values...)
+	}
+
+	c.Writer = buffered
+	next(c)
+	c.Writer = writer
+
+	if buffered.Exceeded() {
+		return buffered.Status(), false
+	}
+	if buffered.Status() >= http.StatusInternalServerError || len(c.Errors) > 0 {
+		cache.doCacheHit(c, fallback.cacheable, fallback.response)
+		return buffered.Status(), true
+	}
+	buffered.Replay(writer)
+	return buffered.Status(), false
 }
 
-func (cache *CacheHandler) doCacheEvict(ctx context.Context, c *gin.Context, cacheEvicts ...CacheEvict) {
-	keys := make([]string, 0)
-	params := utils.ParameterParser(c)
+// getCacheKey by GenKey, the canonical key of the request when it is nil
+func (cache *CacheHandler) getCacheKey(cacheable Cacheable, params map[string]interface{}, c *gin.Context) string {
+	var key string
+	if cacheable.GenKey != nil {
+		key = strings.ToLower(cacheable.GenKey(params))
+	} else {
+		// path and query values are case-sensitive
+		key = defaultKey(c, cacheable.HashBody)
+	}
+	if key != "" && len(cacheable.Vary) > 0 {
+		key += utils.VaryKey(c.Request.Header, cacheable.Vary)
+	}
+	return key
+}
+
+// loadCache load and decode the envelope, nil with ErrCacheMiss means miss,
+// a failure of the driver is reported and returned with nil
+func (cache *CacheHandler) loadCache(ctx context.Context, key string) (*entity.Response, error) {
+	opCtx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+
+	key = cache.namespaced(key)
+	data, err := loadBytes(opCtx, cache.Cache, key)
+	if err == nil && len(data) == 0 {
+		err = ErrCacheMiss
+	}
+	if err != nil {
+		cache.reportError(ctx, OpLoad, []string{key}, err)
+		return nil, err
+	}
+	response, err := entity.DecodeResponseBytes(data)
+	if err != nil {
+		// an entry of an unknown version is replaced on the next store
+		return nil, ErrCacheMiss
+	}
+	return response, nil
+}
+
+// available false when the driver tells the backend is down
+func (cache *CacheHandler) available() bool {
+	if driver, ok := cache.Cache.(interface{ Available() bool }); ok {
+		return driver.Available()
+	}
+	return true
+}
+
+// reportError pass a failure of the driver to OnCacheError, a miss or a call rejected by the breaker is not a failure
+func (cache *CacheHandler) reportError(ctx context.Context, op string, keys []string, err error) {
+	if err != nil && err != ErrCacheMiss && err != ErrCircuitOpen && cache.OnCacheError != nil {
+		cache.OnCacheError(ctx, op, keys, err)
+	}
+}
+
+// shouldCache by default only 2xx or statuses in StatusCacheTime, without errors, the 2xx not aborted
+func (cache *CacheHandler) shouldCache(c *gin.Context, cacheable Cacheable, response *entity.Response) bool {
+	if !cache.policyAllowsStore(c) || responseExceeded(c) {
+		return false
+	}
+	if maxAge, ok := cache.policyCacheTime(c); ok && maxAge == 0 && cache.cacheTime(c, cacheable, response.Status) == 0 {
+		return false
+	}
+	if cacheable.ShouldCache != nil {
+		return cacheable.ShouldCache(c, response.Status, response.Body)
+	}
+	if len(c.Errors) > 0 {
+		return false
+	}
+	if _, ok := cacheable.StatusCacheTime[response.Status]; ok {
+		return true
+	}
+	// a detached context is always aborted, see gin.Context.Copy
+	aborted := c.IsAborted() && !c.GetBool(detachedKey)
+	return response.Status >= http.StatusOK && response.Status < http.StatusMultipleChoices && !aborted
+}
+
+// cacheTime by status, then Cacheable.CacheTime, then max-age of the response when the policy allows
+func (cache *CacheHandler) cacheTime(c *gin.Context, cacheable Cacheable, status int) time.Duration {
+	if timeout, ok := cacheable.StatusCacheTime[status]; ok {
+		return timeout
+	}
+	if cacheable.CacheTime == 0 {
+		if maxAge, ok := cache.policyCacheTime(c); ok {
+			return maxAge
+		}
+	}
+	return cacheable.CacheTime
+}
+
+// setCache stamp the soft expiry of the envelope, the driver keeps it for the stale windows longer.
+// returns the stamped envelope, also when the driver failed to store it
+func (cache *CacheHandler) setCache(ctx context.Context, c *gin.Context, key string, response *entity.Response, cacheable Cacheable) *entity.Response {
+	timeout := cache.cacheTime(c, cacheable, response.Status)
+	if timeout == 0 {
+		// the stale windows start when the driver would drop the entry
+		timeout = defaultTimeout(cache.Cache)
+	}
+
+	stored := *response
+	stored.CreateAt = time.Now()
+	stored.ExpireAt = time.Time{}
+	if stored.ETag == "" {
+		stored.ETag = strongETag(stored.Body)
+	}
+	if stored.LastModified.IsZero() && cacheable.LastModified {
+		stored.LastModified = stored.CreateAt.Truncate(time.Second)
+	}
+	cache.compressBody(&stored)
+	if timeout > 0 {
+		stored.ExpireAt = stored.CreateAt.Add(timeout)
+		if cacheable.StaleWhileRevalidate > cacheable.StaleIfError {
+			timeout += cacheable.StaleWhileRevalidate
+		} else {
+			timeout += cacheable.StaleIfError
+		}
+	}
+	opCtx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+	key = cache.namespaced(key)
+	if err := setBytes(opCtx, cache.Cache, key, stored.EncodeBytes(), timeout); err != nil {
+		cache.reportError(ctx, OpSet, []string{key}, err)
+		return &stored
+	}
+	if tags := entryTags(c, cacheable); len(tags) > 0 {
+		cache.tagEntry(ctx, key, tags, timeout)
+	}
+	return &stored
+}
+
+// namespaced key as passed to the driver
+func (cache *CacheHandler) namespaced(key string) string {
+	if cache.KeyPrefix == "" && cache.Generation == 0 {
+		return key
+	}
+	namespace := "v" + strconv.Itoa(cache.Generation) + ":"
+	if cache.KeyPrefix != "" {
+		namespace = cache.KeyPrefix + ":" + namespace
+	}
+	return namespace + key
+}
+
+func (cache *CacheHandler) namespacedKeys(keys []string) []string {
+	namespaced := make([]string, 0, len(keys))
+	for _, key := range keys {
+		namespaced = append(namespaced, cache.namespaced(key))
+	}
+	return namespaced
+}
+
+// withTimeout bound one cache operation by OperationTimeout
+func (cache *CacheHandler) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cache.OperationTimeout > 0 {
+		return context.WithTimeout(ctx, cache.OperationTimeout)
+	}
+	return ctx, func() {}
+}
+
+// captureResponse collect status, whitelisted headers and body written by handler
+func (cache *CacheHandler) captureResponse(c *gin.Context, cacheable Cacheable) *entity.Response {
+	cacheHeaders := cacheable.CacheHeaders
+	if cacheHeaders == nil {
+		cacheHeaders = cache.CacheHeaders
+	}
+
+	header := http.Header{}
+	for _, name := range cacheHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if values := c.Writer.Header()[name]; len(values) > 0 {
+			header[name] = append([]string(nil), values...)
+		}
+	}
+
+	response := &entity.Response{
+		Status: c.Writer.Status(),
+		Header: header,
+		Body:   responseBody(c),
+		ETag:   c.Writer.Header().Get("ETag"),
+	}
+	if lastModified, err := http.ParseTime(c.Writer.Header().Get("Last-Modified")); err == nil {
+		response.LastModified = lastModified
+	}
+	return response
+}
+
+// putCacheables CachePut share the store path of Cacheable
+func putCacheables(puts []CachePut) []Cacheable {
+	cacheables := make([]Cacheable, 0, len(puts))
+	for _, put := range puts {
+		cacheables = append(cacheables, Cacheable(put))
+	}
+	return cacheables
+}
+
+// evictByResponse any rule reads the response, it has to be captured
+func evictByResponse(caching Caching) bool {
+	for _, rule := range caching.EvictRules {
+		if rule.GenKeysByResponse != nil && !rule.BeforeInvocation {
+			return true
+		}
+	}
+	return false
+}
+
+// evictRules Evict in the struct form followed by EvictRules
+func evictRules(caching Caching) []CacheEvictRule {
+	rules := make([]CacheEvictRule, 0, len(caching.Evict)+len(caching.EvictRules))
+	for _, evict := range caching.Evict {
+		rules = append(rules, CacheEvictRule{GenKey: GenKeyFunc(evict)})
+	}
+	return append(rules, caching.EvictRules...)
+}
+
+// doCacheEvict run the rules of one phase, before or after the handler which answered status
+func (cache *CacheHandler) doCacheEvict(ctx context.Context, c *gin.Context, beforeInvocation bool, status int, cacheEvicts ...CacheEvictRule) {
+	success := status >= http.StatusOK && status < http.StatusMultipleChoices
+	rules := make([]CacheEvictRule, 0, len(cacheEvicts))
 	for _, evict := range cacheEvicts {
-		s := evict(params)
-		if s != "" {
-			keys = append(keys, strings.ToLower(s))
+		if evict.BeforeInvocation == beforeInvocation && (beforeInvocation || success || !evict.OnlyOnSuccess) {
+			rules = append(rules, evict)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	keys, tags := make([]string, 0), make([]string, 0)
+	params := utils.ParameterParser(c)
+	var body []byte
+	var decoded map[string]interface{}
+	var captured bool
+	for _, evict := range rules {
+		if evict.Condition != nil && !evict.Condition(params, c) {
+			continue
+		}
+		var genKeys []string
+		if evict.GenKey != nil {
+			genKeys = append(genKeys, evict.GenKey(params))
+		}
+		if evict.GenKeysByResponse != nil && !beforeInvocation {
+			if !captured {
+				body, captured = responseBody(c), true
+				_ = json.Unmarshal(body, &decoded)
+			}
+			genKeys = append(genKeys, evict.GenKeysByResponse(params, decoded, body)...)
+		}
+		for _, s := range genKeys {
+			if s != "" {
+				keys = append(keys, strings.ToLower(s))
+			}
+		}
+		if evict.EvictTags != nil {
+			for _, tag := range evict.EvictTags(params) {
+				if tag != "" {
+					tags = append(tags, tag)
+				}
+			}
 		}
 	}
 
 	if len(keys) > 0 {
-		cache.Cache.DoEvict(ctx, keys)
+		opCtx, cancel := cache.withTimeout(ctx)
+		defer cancel()
+		keys = cache.namespacedKeys(keys)
+		cache.reportError(ctx, OpEvict, keys, cache.Cache.DoEvict(opCtx, keys))
+	}
+	if len(tags) > 0 {
+		cache.evictTags(ctx, tags)
 	}
 }
 
-func (cache *CacheHandler) doCacheHit(ctx *gin.Context, caching Caching, cacheValue string) {
-
-	if len(caching.Cacheable[0].OnCacheHit) > 0 {
-		caching.Cacheable[0].OnCacheHit[0](ctx, cacheValue)
+func (cache *CacheHandler) doCacheHit(ctx *gin.Context, cacheable Cacheable, response *entity.Response) {
+	// Cacheable hooks first, the []byte hook before the string one
+	onCacheHitBytes, onCacheHit := cacheable.OnCacheHitBytes, cacheable.OnCacheHit
+	if onCacheHitBytes == nil && len(onCacheHit) == 0 {
+		onCacheHitBytes, onCacheHit = cache.OnCacheHitBytes, cache.OnCacheHit
+	}
+	if onCacheHitBytes != nil || len(onCacheHit) > 0 {
+		body, err := decodedBody(response)
+		if err != nil {
+			_ = ctx.Error(err)
+		}
+		if onCacheHitBytes != nil {
+			onCacheHitBytes(ctx, body)
+		} else {
+			onCacheHit[0](ctx, string(body))
+		}
 		ctx.Abort()
 		return
 	}
 
-	if len(cache.OnCacheHit) > 0 {
-		cache.OnCacheHit[0](ctx, cacheValue)
+	// default hit startup, replay status, headers and body
+	for name, values := range response.Header {
+		ctx.Writer.Header()[name] = values
+	}
+	// the gzip representation has a validator of its own
+	gzipped := response.ContentEncoding == encodingGzip && acceptsGzip(ctx.Request)
+	etag := response.ETag
+	if gzipped {
+		etag = gzipETag(etag)
+	}
+	if etag != "" {
+		ctx.Header("ETag", etag)
+	}
+	if !response.LastModified.IsZero() {
+		ctx.Header("Last-Modified", response.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if response.ContentEncoding != "" {
+		addVary(ctx, "Accept-Encoding")
+	}
+	if notModified(ctx.Request, response, etag) {
+		ctx.Status(http.StatusNotModified)
+		ctx.Writer.WriteHeaderNow()
 		ctx.Abort()
 		return
 	}
-
-	// default hit startup
-	ctx.Writer.Header().Set("Content-Type", "application/json; Charset=utf-8")
-	ctx.String(http.StatusOK, cacheValue)
+	ctx.Status(response.Status)
+	writeBody(ctx, response, gzipped)
 	ctx.Abort()
 }
 
+// responseBody captured by the writer of c, nil when it is not captured
+func responseBody(c *gin.Context) []byte {
+	switch writer := c.Writer.(type) {
+	case *pkg.ResponseBodyWriter:
+		return writer.Body.Bytes()
+	case *pkg.BufferedResponseWriter:
+		return writer.Body.Bytes()
+	}
+	return nil
+}
+
 func refreshBodyData(c *gin.Context) {
 	if c.Request.Body != nil {
 		bodyStr, exists := c.Get(bodyBytesKey)
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/entity"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const encodingGzip = "gzip"
+
+// compressBody gzip the body of an entry about to be stored, kept raw when it is small,
+// already encoded by the handler or does not shrink
+func (cache *CacheHandler) compressBody(response *entity.Response) {
+	if cache.CompressMinSize <= 0 || len(response.Body) < cache.CompressMinSize {
+		return
+	}
+	if response.ContentEncoding != "" || response.Header.Get("Content-Encoding") != "" {
+		return
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(response.Body); err != nil || writer.Close() != nil {
+		return
+	}
+	if buf.Len() < len(response.Body) {
+		response.Body = buf.Bytes()
+		response.ContentEncoding = encodingGzip
+	}
+}
+
+// decodedBody the body as written by the handler
+func decodedBody(response *entity.Response) ([]byte, error) {
+	if response.ContentEncoding != encodingGzip {
+		return response.Body, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(response.Body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// writeBody send the stored body, compressed as is when gzipped
+func writeBody(c *gin.Context, response *entity.Response, gzipped bool) {
+	if response.ContentEncoding == "" {
+		_, _ = c.Writer.Write(response.Body)
+		return
+	}
+
+	if gzipped {
+		c.Header("Content-Encoding", encodingGzip)
+		_, _ = c.Writer.Write(response.Body)
+		return
+	}
+	body, err := decodedBody(response)
+	if err != nil {
+		_ = c.Error(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	_, _ = c.Writer.Write(body)
+}
+
+// gzipETag validator of the gzip representation, derived from the one of the identity body
+func gzipETag(etag string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + `-gzip"`
+}
+
+// acceptsGzip Accept-Encoding lists gzip or *, without q=0
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header["Accept-Encoding"] {
+		for _, coding := range strings.Split(value, ",") {
+			parts := strings.Split(coding, ";")
+			name := strings.ToLower(strings.TrimSpace(parts[0]))
+			if name != encodingGzip && name != "*" {
+				continue
+			}
+			accepted := true
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					q, err := strconv.ParseFloat(param[2:], 64)
+					accepted = err == nil && q > 0
+				}
+			}
+			if accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addVary add name to the Vary header of the response once
+func addVary(c *gin.Context, name string) {
+	for _, value := range c.Writer.Header()["Vary"] {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), name) {
+				return
+			}
+		}
+	}
+	c.Writer.Header().Add("Vary", name)
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pygzfei/gin-cache/internal/entity"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// strongETag of the body, computed once when the entry is stored
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluate If-None-Match against etag of the representation sent, then If-Modified-Since against the cached entry
+func notModified(r *http.Request, response *entity.Response, etag string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if response.Status < http.StatusOK || response.Status >= http.StatusMultipleChoices {
+		return false
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !response.LastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !response.LastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/utils"
+	"net/url"
+	"strings"
+)
+
+// defaultKey canonical key of a Cacheable without GenKey, unique per route:
+// method:route|path params|sorted query, and |body hash when hashBody is set.
+// the method, route and param names are lowercase like evict patterns, the values keep their case
+func defaultKey(c *gin.Context, hashBody bool) string {
+	route := utils.FullPath(c)
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+
+	params := url.Values{}
+	for _, param := range c.Params {
+		params.Set(strings.ToLower(param.Key), param.Value)
+	}
+	// parse and encode again, the keys are sorted and the escaping is normalized
+	query, _ := url.ParseQuery(c.Request.URL.RawQuery)
+
+	key := strings.Join([]string{strings.ToLower(c.Request.Method + ":" + route), params.Encode(), query.Encode()}, "|")
+	if hashBody {
+		if body, ok := c.Get(bodyBytesKey); ok && len(body.([]byte)) > 0 {
+			key += "|" + bodyHash(body.([]byte))
+		}
+	}
+	return key
+}
+
+// bodyHash of the canonical json, the field order and spacing do not change it. other bodies are hashed as is
+func bodyHash(body []byte) string {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err == nil && !decoder.More() {
+		body, _ = json.Marshal(decoded)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"time"
+)
+
+// CacheV2 error-aware driver, Load returns define.ErrCacheMiss for a missing key,
+// any other error is a backend failure and the request falls through to the handler
+type CacheV2 interface {
+	Load(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, data string, timeout time.Duration) error
+	DoEvict(ctx context.Context, keys []string) error
+}
+
+// BytesCache optional for a CacheV2 storing []byte, the envelope is passed without string conversions
+type BytesCache interface {
+	LoadBytes(ctx context.Context, key string) ([]byte, error)
+	SetBytes(ctx context.Context, key string, data []byte, timeout time.Duration) error
+}
+
+// loadBytes through BytesCache when the driver implements it
+func loadBytes(ctx context.Context, c CacheV2, key string) ([]byte, error) {
+	if driver, ok := c.(BytesCache); ok {
+		return driver.LoadBytes(ctx, key)
+	}
+	data, err := c.Load(ctx, key)
+	return []byte(data), err
+}
+
+// setBytes through BytesCache when the driver implements it
+func setBytes(ctx context.Context, c CacheV2, key string, data []byte, timeout time.Duration) error {
+	if driver, ok := c.(BytesCache); ok {
+		return driver.SetBytes(ctx, key, data, timeout)
+	}
+	return c.Set(ctx, key, string(data), timeout)
+}
+
+// TagCache optional for a CacheV2 indexing entries by tag,
+// a tag is the name of its index, namespaced like the keys
+type TagCache interface {
+	// Tag add key to the index of each tag, the index lives at least for timeout
+	Tag(ctx context.Context, key string, tags []string, timeout time.Duration) error
+	// EvictTags delete every entry in the index of the tags, and the indexes
+	EvictTags(ctx context.Context, tags []string) error
+}
+
+// defaultTimeout of a driver keeping an entry stored without timeout for a default time, 0 keeps it forever
+func defaultTimeout(c interface{}) time.Duration {
+	if driver, ok := c.(interface{ DefaultTimeout() time.Duration }); ok {
+		return driver.DefaultTimeout()
+	}
+	return 0
+}
+
+// AdaptCache wrap a driver without errors, every empty value is a miss and operations never fail
+func AdaptCache(c Cache) CacheV2 {
+	return cacheAdapter{cache: c}
+}
+
+type cacheAdapter struct {
+	cache Cache
+}
+
+func (a cacheAdapter) Load(ctx context.Context, key string) (string, error) {
+	data := a.cache.Load(ctx, key)
+	if data == "" {
+		return "", ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (a cacheAdapter) Set(ctx context.Context, key string, data string, timeout time.Duration) error {
+	a.cache.Set(ctx, key, data, timeout)
+	return nil
+}
+
+func (a cacheAdapter) DoEvict(ctx context.Context, keys []string) error {
+	a.cache.DoEvict(ctx, keys)
+	return nil
+}
+
+func (a cacheAdapter) DefaultTimeout() time.Duration {
+	return defaultTimeout(a.cache)
+}
@@ -3,6 +3,7 @@ package memcache
 import (
 	"context"
 	"github.com/pygzfei/gin-cache/internal/entity"
+	"github.com/pygzfei/gin-cache/pkg/define"
 	"strings"
 	"sync"
 	"time"
@@ -11,19 +12,21 @@ import (
 // memoryHandler is private
 type memoryHandler struct {
 	cacheStore sync.Map
+	tagMu      sync.Mutex
+	tags       map[string]map[string]time.Time // expiry of keys by tag
 }
 
 // NewMemoryHandler do new memory startup object
 func NewMemoryHandler() *memoryHandler {
 	memoryHandler := &memoryHandler{
 		cacheStore: sync.Map{},
+		tags:       make(map[string]map[string]time.Time),
 	}
 
 	timer := time.NewTicker(time.Second * 30)
 
 	go func() {
-		select {
-		case <-timer.C:
+		for range timer.C {
 			memoryHandler.cacheStore.Range(func(key, value interface{}) bool {
 				item := value.(entity.CacheItem)
 				if item.ExpireAt.UnixNano() < time.Now().UnixNano() {
@@ -31,26 +34,38 @@ func NewMemoryHandler() *memoryHandler {
 				}
 				return true
 			})
+			memoryHandler.pruneTags()
 		}
 	}()
 
 	return memoryHandler
 }
 
-func (m *memoryHandler) Load(_ context.Context, key string) string {
+func (m *memoryHandler) Load(ctx context.Context, key string) (string, error) {
+	data, err := m.LoadBytes(ctx, key)
+	return string(data), err
+}
+
+// LoadBytes the stored slice itself, it must not be modified
+func (m *memoryHandler) LoadBytes(_ context.Context, key string) ([]byte, error) {
 	load, ok := m.cacheStore.Load(key)
 	if ok {
 		item := load.(entity.CacheItem)
 		if item.ExpireAt.UnixNano() < time.Now().UnixNano() {
 			m.cacheStore.Delete(key)
-			return ""
+			return nil, define.ErrCacheMiss
 		}
-		return item.Value
+		return item.Value, nil
 	}
-	return ""
+	return nil, define.ErrCacheMiss
+}
+
+func (m *memoryHandler) Set(ctx context.Context, key string, data string, timeout time.Duration) error {
+	return m.SetBytes(ctx, key, []byte(data), timeout)
 }
 
-func (m *memoryHandler) Set(_ context.Context, key string, data string, timeout time.Duration) {
+// SetBytes keep data without a copy
+func (m *memoryHandler) SetBytes(_ context.Context, key string, data []byte, timeout time.Duration) error {
 	if timeout > 0 {
 		m.cacheStore.Store(key, entity.CacheItem{
 			Value:    data,
@@ -64,10 +79,10 @@ func (m *memoryHandler) Set(_ context.Context, key string, data string, timeout
 			ExpireAt: time.Now().Add(time.Hour * 1000000),
 		})
 	}
-
+	return nil
 }
 
-func (m *memoryHandler) DoEvict(_ context.Context, keys []string) {
+func (m *memoryHandler) DoEvict(_ context.Context, keys []string) error {
 	var evictKeys []string
 	for _, key := range keys {
 		isEndingStar := key[len(key)-1:]
@@ -89,4 +104,77 @@ func (m *memoryHandler) DoEvict(_ context.Context, keys []string) {
 	for _, key := range evictKeys {
 		m.cacheStore.Delete(key)
 	}
+
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+	m.untag(evictKeys)
+	return nil
+}
+
+// Tag index key under each tag until it expires
+func (m *memoryHandler) Tag(_ context.Context, key string, tags []string, timeout time.Duration) error {
+	expireAt := time.Now().Add(time.Hour * 1000000)
+	if timeout > 0 {
+		expireAt = time.Now().Add(timeout)
+	}
+
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+	for _, tag := range tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]time.Time)
+		}
+		m.tags[tag][key] = expireAt
+	}
+	return nil
+}
+
+func (m *memoryHandler) EvictTags(_ context.Context, tags []string) error {
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+	now := time.Now()
+	var evictKeys []string
+	for _, tag := range tags {
+		for key, expireAt := range m.tags[tag] {
+			// an expired index entry may point to a key stored again without the tag
+			if expireAt.After(now) {
+				m.cacheStore.Delete(key)
+			}
+			evictKeys = append(evictKeys, key)
+		}
+	}
+	m.untag(evictKeys)
+	return nil
+}
+
+// pruneTags drop keys which expired or are no longer stored, e.g. after a generation bump
+func (m *memoryHandler) pruneTags() {
+	now := time.Now()
+	m.tagMu.Lock()
+	defer m.tagMu.Unlock()
+	for tag, keys := range m.tags {
+		for key, expireAt := range keys {
+			if _, ok := m.cacheStore.Load(key); !ok || expireAt.Before(now) {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
+
+// untag remove keys from every tag, tagMu must be held
+func (m *memoryHandler) untag(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	for tag, indexed := range m.tags {
+		for _, key := range keys {
+			delete(indexed, key)
+		}
+		if len(indexed) == 0 {
+			delete(m.tags, tag)
+		}
+	}
 }
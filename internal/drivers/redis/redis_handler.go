@@ -3,7 +3,9 @@ package redis
 import (
 	"context"
 	"github.com/go-redis/redis/v8"
+	"github.com/pygzfei/gin-cache/pkg/define"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -17,30 +19,120 @@ func NewRedisHandler(client *redis.Client, cacheTime time.Duration) *redisCache
 	return &redisCache{cacheStore: client, cacheTime: cacheTime}
 }
 
-func (r *redisCache) Load(ctx context.Context, key string) string {
-	return r.cacheStore.Get(ctx, key).Val()
+// DefaultTimeout of an entry stored without timeout
+func (r *redisCache) DefaultTimeout() time.Duration {
+	return r.cacheTime
 }
 
-func (r *redisCache) Set(ctx context.Context, key string, data string, timeout time.Duration) {
+func (r *redisCache) Load(ctx context.Context, key string) (string, error) {
+	data, err := r.cacheStore.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", define.ErrCacheMiss
+	}
+	return data, err
+}
+
+func (r *redisCache) LoadBytes(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.cacheStore.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, define.ErrCacheMiss
+	}
+	return data, err
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, data string, timeout time.Duration) error {
+	return r.set(ctx, key, data, timeout)
+}
+
+func (r *redisCache) SetBytes(ctx context.Context, key string, data []byte, timeout time.Duration) error {
+	return r.set(ctx, key, data, timeout)
+}
+
+func (r *redisCache) set(ctx context.Context, key string, data interface{}, timeout time.Duration) error {
 	if timeout > 0 {
-		r.cacheStore.Set(ctx, key, data, timeout)
-	} else {
-		r.cacheStore.Set(ctx, key, data, r.cacheTime)
+		return r.cacheStore.Set(ctx, key, data, timeout).Err()
 	}
+	return r.cacheStore.Set(ctx, key, data, r.cacheTime).Err()
 }
 
-func (r *redisCache) DoEvict(ctx context.Context, keys []string) {
+func (r *redisCache) DoEvict(ctx context.Context, keys []string) error {
 	var evictKeys []string
+	var scanErr error
 	for _, key := range keys {
 		var cursor uint64
 		deleteKeys, _, err := r.cacheStore.Scan(ctx, cursor, key, math.MaxUint16).Result()
 
 		if err == nil {
 			evictKeys = append(evictKeys, deleteKeys...)
+		} else if scanErr == nil {
+			scanErr = err
 		}
 	}
 
 	if len(evictKeys) > 0 {
-		r.cacheStore.Del(ctx, evictKeys...)
+		if err := r.cacheStore.Del(ctx, evictKeys...).Err(); err != nil {
+			return err
+		}
+	}
+	return scanErr
+}
+
+// Tag add key to the sorted set of each tag scored by its expiry, expired members are trimmed
+// and a set expires with the longest lived entry in it
+func (r *redisCache) Tag(ctx context.Context, key string, tags []string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = r.cacheTime
+	}
+	now := time.Now()
+	expireAt := math.Inf(1)
+	if timeout > 0 {
+		expireAt = float64(now.Add(timeout).UnixNano() / int64(time.Millisecond))
 	}
+	last := make([]*redis.ZSliceCmd, len(tags))
+	_, err := r.cacheStore.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tag := range tags {
+			pipe.ZRemRangeByScore(ctx, tag, "-inf", scoreOf(now))
+			pipe.ZAdd(ctx, tag, &redis.Z{Score: expireAt, Member: key})
+			last[i] = pipe.ZRangeWithScores(ctx, tag, -1, -1)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.cacheStore.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tag := range tags {
+			members := last[i].Val()
+			if len(members) == 0 {
+				continue
+			}
+			if score := members[0].Score; math.IsInf(score, 1) {
+				pipe.Persist(ctx, tag)
+			} else {
+				pipe.PExpireAt(ctx, tag, time.Unix(0, int64(score)*int64(time.Millisecond)))
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// EvictTags delete the live members of the tags and the sets
+func (r *redisCache) EvictTags(ctx context.Context, tags []string) error {
+	var evictKeys []string
+	min := "(" + scoreOf(time.Now())
+	for _, tag := range tags {
+		keys, err := r.cacheStore.ZRangeByScore(ctx, tag, &redis.ZRangeBy{Min: min, Max: "+inf"}).Result()
+		if err != nil {
+			return err
+		}
+		evictKeys = append(evictKeys, keys...)
+		evictKeys = append(evictKeys, tag)
+	}
+	return r.cacheStore.Del(ctx, evictKeys...).Err()
+}
+
+// scoreOf t in unix milliseconds
+func scoreOf(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
 }
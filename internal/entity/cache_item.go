@@ -3,7 +3,7 @@ package entity
 import "time"
 
 type CacheItem struct {
-	Value    string
+	Value    []byte
 	CreateAt time.Time
 	ExpireAt time.Time
 	Hits     uint64
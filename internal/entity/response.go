@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ResponseVersion current version of the cached response envelope, 2 added ContentEncoding
+const ResponseVersion = 2
+
+// responseMagic marks a value written as envelope, values without it are legacy raw bodies
+var responseMagic = []byte("\x00GCE")
+
+// ErrResponseVersion the envelope was written by an unknown version
+var ErrResponseVersion = errors.New("gin-cache: unsupported cache entry version")
+
+// Response is the envelope stored by drivers for a cached http response
+//
+// layout: magic | version(1 byte) | meta length(4 bytes) | meta json | body
+type Response struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"-"`
+	CreateAt time.Time   `json:"create_at"`
+	ExpireAt time.Time   `json:"expire_at"` // soft expiry, zero means fresh until the driver drops it
+
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified"` // zero when neither the handler nor Cacheable.LastModified set it
+
+	ContentEncoding string `json:"content_encoding,omitempty"` // encoding of Body in the store, "" raw or gzip
+}
+
+// IsFresh the entry has not passed its soft expiry
+func (r *Response) IsFresh(now time.Time) bool {
+	return r.ExpireAt.IsZero() || now.Before(r.ExpireAt)
+}
+
+// Staleness how long ago the entry passed its soft expiry
+func (r *Response) Staleness(now time.Time) time.Duration {
+	if r.IsFresh(now) {
+		return 0
+	}
+	return now.Sub(r.ExpireAt)
+}
+
+// Encode the response into the versioned envelope
+func (r *Response) Encode() string {
+	return string(r.EncodeBytes())
+}
+
+// EncodeBytes the envelope for drivers storing []byte
+func (r *Response) EncodeBytes() []byte {
+	meta, _ := json.Marshal(r)
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(responseMagic)+5+len(meta)+len(r.Body)))
+	buf.Write(responseMagic)
+	buf.WriteByte(ResponseVersion)
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(meta)))
+	buf.Write(size)
+	buf.Write(meta)
+	buf.Write(r.Body)
+	return buf.Bytes()
+}
+
+// DecodeResponse parse a value loaded from driver,
+// values written before the envelope existed are treated as a json body with status 200
+func DecodeResponse(data string) (*Response, error) {
+	return DecodeResponseBytes([]byte(data))
+}
+
+// DecodeResponseBytes parse a value loaded as []byte, Body shares the memory of raw
+func DecodeResponseBytes(raw []byte) (*Response, error) {
+	if !bytes.HasPrefix(raw, responseMagic) {
+		return &Response{
+			Status: http.StatusOK,
+			Header: http.Header{"Content-Type": []string{"application/json; Charset=utf-8"}},
+			Body:   raw,
+		}, nil
+	}
+
+	raw = raw[len(responseMagic):]
+	if len(raw) < 5 || raw[0] < 1 || raw[0] > ResponseVersion {
+		return nil, ErrResponseVersion
+	}
+	size := binary.BigEndian.Uint32(raw[1:5])
+	raw = raw[5:]
+	if uint32(len(raw)) < size {
+		return nil, ErrResponseVersion
+	}
+
+	response := &Response{}
+	if err := json.Unmarshal(raw[:size], response); err != nil {
+		return nil, err
+	}
+	response.Body = raw[size:]
+	return response, nil
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/utils"
+	"github.com/pygzfei/gin-cache/pkg"
+	"io"
+	"io/ioutil"
+)
+
+// bufferBody keep the request body in memory for params and replays, false when it is beyond MaxRequestBodySize.
+// an oversized body is left streaming, only the bytes read to detect it are put back in front
+func (cache *CacheHandler) bufferBody(c *gin.Context) bool {
+	if c.Request.Body == nil {
+		return true
+	}
+	limit := cache.MaxRequestBodySize
+	if limit > 0 && c.Request.ContentLength > limit {
+		c.Set(utils.SkipBodyKey, true)
+		return false
+	}
+
+	reader := io.Reader(c.Request.Body)
+	if limit > 0 {
+		reader = io.LimitReader(c.Request.Body, limit+1)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		body = []byte("")
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		c.Set(utils.SkipBodyKey, true)
+		c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body), Closer: c.Request.Body}
+		return false
+	}
+	c.Set(bodyBytesKey, body)
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return true
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// responseExceeded the captured response grew beyond MaxResponseBodySize
+func responseExceeded(c *gin.Context) bool {
+	switch writer := c.Writer.(type) {
+	case *pkg.ResponseBodyWriter:
+		return writer.Exceeded()
+	case *pkg.BufferedResponseWriter:
+		return writer.Exceeded()
+	}
+	return false
+}
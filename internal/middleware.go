@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"github.com/gin-gonic/gin"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"strings"
+)
+
+// Middleware apply the Caching of the matching rule in the gin chain, for r.Use and route groups.
+// requests of routes without a rule pass through
+func (cache *CacheHandler) Middleware(rules []RouteRule) gin.HandlerFunc {
+	table := newRuleTable(rules)
+	return cache.middleware(func() ruleTable {
+		return table
+	})
+}
+
+// middleware match against the table in use when the request arrives
+func (cache *CacheHandler) middleware(rules func() ruleTable) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caching, ok := rules().match(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+		cache.Handler(caching, chainNext(c.Handler()))(c)
+	}
+}
+
+// ruleTable RouteRule by method and path
+type ruleTable map[string]Caching
+
+func newRuleTable(rules []RouteRule) ruleTable {
+	table := make(ruleTable, len(rules))
+	for _, rule := range rules {
+		table[ruleKey(rule.Method, rule.Path)] = rule.Caching
+	}
+	return table
+}
+
+// match the rule of the method first, then the one for every method
+func (t ruleTable) match(method string, path string) (Caching, bool) {
+	if path == "" {
+		return Caching{}, false
+	}
+	if caching, ok := t[ruleKey(method, path)]; ok {
+		return caching, true
+	}
+	caching, ok := t[ruleKey("", path)]
+	return caching, ok
+}
+
+func ruleKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// chainNext continue the gin chain, a detached copy has no chain and runs only the route handler
+func chainNext(route gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(detachedKey) {
+			route(c)
+			return
+		}
+		c.Next()
+	}
+}
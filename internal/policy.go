@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/utils"
+	"strings"
+	"time"
+)
+
+// bypassLookup the client asks for a response not served from cache
+func (cache *CacheHandler) bypassLookup(c *gin.Context) bool {
+	if cache.HTTPPolicy == nil || !cache.HTTPPolicy.RequestNoCache {
+		return false
+	}
+	if _, ok := utils.ParseCacheControl(c.Request.Header["Cache-Control"])["no-cache"]; ok {
+		return true
+	}
+	return strings.Contains(strings.ToLower(c.Request.Header.Get("Pragma")), "no-cache")
+}
+
+// policyAllowsStore check the request and the full response headers against the policy
+func (cache *CacheHandler) policyAllowsStore(c *gin.Context) bool {
+	policy := cache.HTTPPolicy
+	if policy == nil {
+		return true
+	}
+	header := c.Writer.Header()
+	if policy.SetCookie && len(header["Set-Cookie"]) > 0 {
+		return false
+	}
+	if policy.NoStore {
+		response := utils.ParseCacheControl(header["Cache-Control"])
+		for _, directive := range []string{"no-store", "private"} {
+			if _, ok := response[directive]; ok {
+				return false
+			}
+		}
+		if _, ok := utils.ParseCacheControl(c.Request.Header["Cache-Control"])["no-store"]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// policyCacheTime the s-maxage or max-age of the response, ok is false when the policy does not apply
+func (cache *CacheHandler) policyCacheTime(c *gin.Context) (time.Duration, bool) {
+	if cache.HTTPPolicy == nil || !cache.HTTPPolicy.MaxAge {
+		return 0, false
+	}
+	return utils.MaxAge(utils.ParseCacheControl(c.Writer.Header()["Cache-Control"]))
+}
@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/utils"
+	"github.com/pygzfei/gin-cache/pkg"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	defaultRefreshWorkers   = 4
+	defaultRefreshQueueSize = 64
+)
+
+// refresher bounded worker pool for background revalidation, one pending job per key
+type refresher struct {
+	once    sync.Once
+	jobs    chan refreshJob
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+type refreshJob struct {
+	key string
+	run func()
+}
+
+func (r *refresher) start(workers, queueSize int) {
+	if workers <= 0 {
+		workers = defaultRefreshWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultRefreshQueueSize
+	}
+	r.jobs = make(chan refreshJob, queueSize)
+	r.pending = make(map[string]struct{})
+	for i := 0; i < workers; i++ {
+		go r.work()
+	}
+}
+
+func (r *refresher) work() {
+	for job := range r.jobs {
+		r.run(job)
+	}
+}
+
+func (r *refresher) run(job refreshJob) {
+	defer func() {
+		_ = recover() // a failing refresh keeps the stale entry, the worker must survive
+		r.mu.Lock()
+		delete(r.pending, job.key)
+		r.mu.Unlock()
+	}()
+	job.run()
+}
+
+// submit returns false when key is already refreshing or the queue is full
+func (r *refresher) submit(key string, run func()) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pending[key]; ok {
+		return false
+	}
+	select {
+	case r.jobs <- refreshJob{key: key, run: run}:
+		r.pending[key] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// revalidate replay the request through next in the background and store the fresh response
+func (cache *CacheHandler) revalidate(c *gin.Context, key string, cacheable Cacheable, next gin.HandlerFunc) bool {
+	cache.refresher.once.Do(func() {
+		cache.refresher.start(cache.RefreshWorkers, cache.RefreshQueueSize)
+	})
+
+	cp := detachContext(c, cache.MaxResponseBodySize)
+	return cache.refresher.submit(key, func() {
+		next(cp)
+		if response := cache.captureResponse(cp, cacheable); cache.shouldCache(cp, cacheable, response) {
+			cache.setCache(context.Background(), cp, key, response, cacheable)
+		}
+	})
+}
+
+// detachContext copy of c which outlives the request, the response is only buffered up to limit.
+// the copy keeps the route pattern for the params, c.FullPath() of it is still ""
+func detachContext(c *gin.Context, limit int) *gin.Context {
+	cp := c.Copy()
+	cp.Set(utils.FullPathKey, c.FullPath())
+	cp.Request = c.Request.Clone(context.Background())
+	if body, ok := c.Get(bodyBytesKey); ok {
+		cp.Request.Body = ioutil.NopCloser(bytes.NewReader(body.([]byte)))
+	}
+	writer := pkg.NewBufferedResponseWriter()
+	writer.Limit = limit
+	cp.Writer = writer
+	cp.Set(detachedKey, true)
+	return cp
+}
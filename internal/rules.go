@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ParseRules decode a rule file, .yaml and .yml are yaml, anything else json
+func ParseRules(name string, data []byte) ([]RouteRule, error) {
+	var config RulesConfig
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &config)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gin-cache: decode %s: %w", name, err)
+	}
+	return BuildRules(config.Rules)
+}
+
+// BuildRules turn configs into RouteRule, every rule is checked before any is returned
+func BuildRules(configs []RuleConfig) ([]RouteRule, error) {
+	rules := make([]RouteRule, 0, len(configs))
+	for i, config := range configs {
+		caching, err := buildCaching(config)
+		if err != nil {
+			return nil, fmt.Errorf("gin-cache: rule %d (%s %s): %w", i, config.Method, config.Path, err)
+		}
+		rules = append(rules, RouteRule{Method: config.Method, Path: config.Path, Caching: caching})
+	}
+	return rules, nil
+}
+
+func buildCaching(config RuleConfig) (Caching, error) {
+	var caching Caching
+	if config.Path == "" {
+		return caching, errors.New("path is required")
+	}
+	if config.Key == "" && len(config.Evict) == 0 {
+		return caching, errors.New("neither key nor evict is set")
+	}
+
+	if config.Key != "" {
+		key, err := CompileKey(config.Key)
+		if err != nil {
+			return caching, err
+		}
+		cacheable := Cacheable{GenKey: key.GenKey(), Vary: config.Vary}
+		if config.TTL != "" {
+			ttl, err := time.ParseDuration(config.TTL)
+			if err != nil {
+				return caching, fmt.Errorf("ttl: %w", err)
+			}
+			cacheable.CacheTime = ttl
+		}
+		caching.Cacheable = []Cacheable{cacheable}
+	}
+	for _, pattern := range config.Evict {
+		key, err := CompileKey(pattern)
+		if err != nil {
+			return caching, err
+		}
+		caching.Evict = append(caching.Evict, CacheEvict(key.GenKey()))
+	}
+	return caching, nil
+}
+
+// RuleFile route rules of a yaml or json file, reloaded while serving
+type RuleFile struct {
+	Path          string
+	OnReloadError func(err error) // a failed reload keeps the rules in use
+
+	table   atomic.Value // ruleTable
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// LoadRuleFile read path, an invalid file is an error at startup
+func LoadRuleFile(path string) (*RuleFile, error) {
+	file := &RuleFile{Path: path}
+	if err := file.Reload(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Reload read the file again, the rules in use are replaced only when all new rules are valid
+func (f *RuleFile) Reload() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+	rules, err := ParseRules(f.Path, data)
+	if err != nil {
+		return err
+	}
+	f.table.Store(newRuleTable(rules))
+	f.modTime = info.ModTime()
+	return nil
+}
+
+// Watch reload the file when its modification time changes, until ctx is done
+func (f *RuleFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var failed time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, changed := f.changed()
+			if !changed || modTime.Equal(failed) {
+				continue
+			}
+			// a broken file is reported once, until it is written again
+			if err := f.Reload(); err != nil {
+				failed = modTime
+				if f.OnReloadError != nil {
+					f.OnReloadError(err)
+				}
+			}
+		}
+	}
+}
+
+func (f *RuleFile) changed() (time.Time, bool) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return info.ModTime(), !info.ModTime().Equal(f.modTime)
+}
+
+func (f *RuleFile) rules() ruleTable {
+	return f.table.Load().(ruleTable)
+}
+
+// FileMiddleware Middleware with the current rules of the file
+func (cache *CacheHandler) FileMiddleware(file *RuleFile) gin.HandlerFunc {
+	return cache.middleware(file.rules)
+}
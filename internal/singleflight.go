@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"github.com/pygzfei/gin-cache/internal/entity"
+	"sync"
+	"time"
+)
+
+// defaultWaitTimeout used when Cacheable.WaitTimeout is zero
+const defaultWaitTimeout = 5 * time.Second
+
+// flight one in-progress miss of a key, waiters block until done is closed
+type flight struct {
+	done     chan struct{}
+	response *entity.Response // set by the leader only when it is shareable
+}
+
+// wait for the leader, nil means the leader failed or the wait timed out
+func (f *flight) wait(ctx context.Context, timeout time.Duration) *entity.Response {
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-f.done:
+		return f.response
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// flightGroup coalesce concurrent misses of the same key in the process
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// join returns the flight of key, leader is true when the caller has to compute the value
+func (g *flightGroup) join(key string) (f *flight, leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	if f, ok := g.flights[key]; ok {
+		return f, false
+	}
+	f = &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	return f, true
+}
+
+// finish release the waiters, must be called by the leader even if it panics
+func (g *flightGroup) finish(key string, f *flight) {
+	g.mu.Lock()
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+	g.mu.Unlock()
+	close(f.done)
+}
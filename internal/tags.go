@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal/utils"
+	. "github.com/pygzfei/gin-cache/pkg/define"
+	"time"
+)
+
+// entryTags of Cacheable.Tags and the tags added by the handler, without duplicates
+func entryTags(c *gin.Context, cacheable Cacheable) []string {
+	var tags []string
+	if cacheable.Tags != nil {
+		tags = cacheable.Tags(utils.ParameterParser(c))
+	}
+	tags = append(tags, CacheTags(c)...)
+
+	seen := make(map[string]bool, len(tags))
+	unique := tags[:0]
+	for _, tag := range tags {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// tagIndexes names of the tag indexes, namespaced like the keys and apart from them
+func (cache *CacheHandler) tagIndexes(tags []string) []string {
+	indexes := make([]string, len(tags))
+	for i, tag := range tags {
+		indexes[i] = cache.namespaced("tag:" + tag)
+	}
+	return indexes
+}
+
+// tagEntry add the driver key to the tag indexes
+func (cache *CacheHandler) tagEntry(ctx context.Context, key string, tags []string, timeout time.Duration) {
+	tags = cache.tagIndexes(tags)
+	driver, ok := cache.Cache.(TagCache)
+	if !ok {
+		cache.reportError(ctx, OpSet, tags, ErrTagsUnsupported)
+		return
+	}
+	opCtx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+	cache.reportError(ctx, OpSet, tags, driver.Tag(opCtx, key, tags, timeout))
+}
+
+// evictTags delete the entries of the tags
+func (cache *CacheHandler) evictTags(ctx context.Context, tags []string) {
+	tags = cache.tagIndexes(tags)
+	driver, ok := cache.Cache.(TagCache)
+	if !ok {
+		cache.reportError(ctx, OpEvict, tags, ErrTagsUnsupported)
+		return
+	}
+	opCtx, cancel := cache.withTimeout(ctx)
+	defer cancel()
+	cache.reportError(ctx, OpEvict, tags, driver.EvictTags(opCtx, tags))
+}
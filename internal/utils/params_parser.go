@@ -3,11 +3,26 @@ package utils
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/pygzfei/gin-cache/pkg/define"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
+// SkipBodyKey set on a context whose request body is too large to be parsed for params
+const SkipBodyKey = "gin-cache/skip-body"
+
+// FullPathKey route pattern of a context copied by gin.Context.Copy, which drops it
+const FullPathKey = "gin-cache/full-path"
+
+// FullPath route pattern of c, also for a copied context
+func FullPath(c *gin.Context) string {
+	if fullPath := c.FullPath(); fullPath != "" {
+		return fullPath
+	}
+	return c.GetString(FullPathKey)
+}
+
 func GetQuery(req *http.Request) map[string]interface{} {
 	m := make(map[string]interface{})
 
@@ -23,31 +38,42 @@ func GetQuery(req *http.Request) map[string]interface{} {
 	return m
 }
 
+// ParameterParser params of path, then query on GET or json body on POST and PUT, merged in one map.
+// the params of each source are also kept apart under define.PathParamsKey, QueryParamsKey and BodyParamsKey
 func ParameterParser(c *gin.Context) map[string]interface{} {
 	m := make(map[string]interface{})
-	split := strings.Split(c.FullPath(), `/`)
+	pathParams := make(map[string]interface{})
+	split := strings.Split(FullPath(c), `/`)
 	params := strings.Split(c.Request.URL.Path, `/`)
 	for i, preKey := range split {
 		if strings.Contains(preKey, ":") {
 			key := strings.ReplaceAll(preKey, ":", "")
 			m[key] = params[i]
+			pathParams[key] = params[i]
 		}
 	}
+	queryParams := GetQuery(c.Request)
+	bodyParams := make(map[string]interface{})
 	if c.Request.Method == http.MethodGet {
 
 		//整合query参数
-		queryParams := GetQuery(c.Request)
 		for key, val := range queryParams {
 			m[key] = val
 		}
-	} else if c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut {
+	} else if (c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut) && c.Request.Body != nil && !c.GetBool(SkipBodyKey) {
 		postMap := make(map[string]interface{})
 		err := c.ShouldBindBodyWith(&postMap, binding.JSON)
 		if err == nil {
 			for key, val := range postMap {
 				m[key] = val
 			}
+			bodyParams = postMap
 		}
 	}
+
+	// set last, a param of the request can not take their place
+	m[define.PathParamsKey] = pathParams
+	m[define.QueryParamsKey] = queryParams
+	m[define.BodyParamsKey] = bodyParams
 	return m
 }
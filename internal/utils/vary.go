@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// VaryKey normalized values of the vary headers, appended to a cache key
+//
+// e.g. `|accept=application/json|accept-language=en,zh`
+func VaryKey(header http.Header, vary []string) string {
+	var builder strings.Builder
+	for _, name := range vary {
+		var tokens []string
+		for _, value := range header[http.CanonicalHeaderKey(name)] {
+			for _, token := range strings.Split(value, ",") {
+				if token = strings.Join(strings.Fields(token), ""); token != "" {
+					tokens = append(tokens, strings.ToLower(token))
+				}
+			}
+		}
+		sort.Strings(tokens)
+
+		builder.WriteString("|")
+		builder.WriteString(strings.ToLower(name))
+		builder.WriteString("=")
+		builder.WriteString(strings.Join(tokens, ","))
+	}
+	return builder.String()
+}
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// BufferedResponseWriter hold the whole response in memory, nothing is sent to a client
+type BufferedResponseWriter struct {
+	Body *bytes.Buffer
+	// Limit once the body grows beyond it, the response held so far is sent to Overflow and the rest streams to it,
+	// without Overflow the body is dropped. 0 no limit
+	Limit    int
+	Overflow http.ResponseWriter
+	header   http.Header
+	status   int
+	exceeded bool
+}
+
+// NewBufferedResponseWriter do new writer for a detached context
+func NewBufferedResponseWriter() *BufferedResponseWriter {
+	return &BufferedResponseWriter{
+		Body:   bytes.NewBufferString(""),
+		header: http.Header{},
+		status: http.StatusOK,
+	}
+}
+
+func (w *BufferedResponseWriter) Header() http.Header {
+	if w.exceeded && w.Overflow != nil {
+		return w.Overflow.Header()
+	}
+	return w.header
+}
+
+func (w *BufferedResponseWriter) Write(b []byte) (int, error) {
+	if !w.exceeded && w.Limit > 0 && w.Body.Len()+len(b) > w.Limit {
+		w.exceeded = true
+		if w.Overflow != nil {
+			w.Replay(w.Overflow)
+		}
+		w.Body = bytes.NewBuffer(nil)
+	}
+	if !w.exceeded {
+		return w.Body.Write(b)
+	}
+	if w.Overflow != nil {
+		return w.Overflow.Write(b)
+	}
+	return len(b), nil
+}
+
+func (w *BufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *BufferedResponseWriter) WriteHeader(code int) {
+	if code > 0 && !w.exceeded {
+		w.status = code
+	}
+}
+
+// Exceeded the body grew beyond Limit, Body is dropped
+func (w *BufferedResponseWriter) Exceeded() bool {
+	return w.exceeded
+}
+
+func (w *BufferedResponseWriter) WriteHeaderNow() {}
+
+func (w *BufferedResponseWriter) Status() int {
+	return w.status
+}
+
+func (w *BufferedResponseWriter) Size() int {
+	return w.Body.Len()
+}
+
+func (w *BufferedResponseWriter) Written() bool {
+	return w.exceeded || w.Body.Len() > 0
+}
+
+func (w *BufferedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("gin-cache: buffered response can not be hijacked")
+}
+
+func (w *BufferedResponseWriter) Flush() {
+	if flusher, ok := w.Overflow.(http.Flusher); ok && w.exceeded {
+		flusher.Flush()
+	}
+}
+
+func (w *BufferedResponseWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *BufferedResponseWriter) Pusher() http.Pusher {
+	return nil
+}
+
+// Replay send the buffered status, headers and body to dst
+func (w *BufferedResponseWriter) Replay(dst http.ResponseWriter) {
+	for name, values := range w.header {
+		dst.Header()[name] = values
+	}
+	dst.WriteHeader(w.status)
+	_, _ = dst.Write(w.Body.Bytes())
+}
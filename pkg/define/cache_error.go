@@ -0,0 +1,44 @@
+package define
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCacheMiss returned by an error-aware driver when the key does not exist
+var ErrCacheMiss = errors.New("gin-cache: cache miss")
+
+// cache operations reported to CacheErrorHook
+const (
+	OpLoad  = "load"
+	OpSet   = "set"
+	OpEvict = "evict"
+)
+
+// CacheErrorHook called when a driver operation fails, a miss is not a failure
+type CacheErrorHook func(ctx context.Context, op string, keys []string, err error)
+
+// ErrCircuitOpen returned without calling the driver while the circuit breaker is open
+var ErrCircuitOpen = errors.New("gin-cache: circuit breaker is open")
+
+// BreakerState state of the circuit breaker around a driver
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls pass through
+	BreakerOpen                         // calls fail with ErrCircuitOpen until the cooldown passed
+	BreakerHalfOpen                     // one trial call decides between closed and open
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// ErrTagsUnsupported the driver keeps no tag index
+var ErrTagsUnsupported = errors.New("gin-cache: driver does not support tags")
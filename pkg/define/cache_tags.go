@@ -0,0 +1,18 @@
+package define
+
+import "github.com/gin-gonic/gin"
+
+// cacheTagsKey tags added by the handler at runtime
+const cacheTagsKey = "gin-cache/tags"
+
+// AddCacheTags tag the entry stored from the response of c, e.g. with ids only known to the handler
+func AddCacheTags(c *gin.Context, tags ...string) {
+	c.Set(cacheTagsKey, append(append([]string(nil), CacheTags(c)...), tags...))
+}
+
+// CacheTags added to c by AddCacheTags
+func CacheTags(c *gin.Context) []string {
+	tags, _ := c.Get(cacheTagsKey)
+	current, _ := tags.([]string)
+	return current
+}
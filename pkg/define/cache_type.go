@@ -5,24 +5,89 @@ import (
 	"time"
 )
 
+// DefaultCacheHeaders response headers stored with the cached body when no whitelist is given
+var DefaultCacheHeaders = []string{"Content-Type", "Content-Disposition", "Content-Language"}
+
 // CacheHitHook startup on hit hook
 type CacheHitHook []func(c *gin.Context, cacheValue string)
 
+// CacheHitBytesHook on hit hook with the body as []byte, it must not be modified
+type CacheHitBytesHook func(c *gin.Context, cacheValue []byte)
+
+// ShouldCacheFunc decide whether the response written by handler is stored
+type ShouldCacheFunc func(c *gin.Context, status int, body []byte) bool
+
 // GenKeyFunc startup on hit hook
 type GenKeyFunc func(params map[string]interface{}) string
 
+// GenTagsFunc tags of an entry, or of the entries to evict, from the params
+type GenTagsFunc func(params map[string]interface{}) []string
+
+// ConditionFunc decide whether a rule applies to the request
+type ConditionFunc func(params map[string]interface{}, c *gin.Context) bool
+
 // CacheEvict do Evict
 type CacheEvict GenKeyFunc
 
+// ResponseGenKeysFunc gen keys from the captured response, response is nil when the body is not a json object
+type ResponseGenKeysFunc func(params map[string]interface{}, response map[string]interface{}, body []byte) []string
+
+// CacheEvictRule struct form of CacheEvict
+type CacheEvictRule struct {
+	GenKey            GenKeyFunc
+	GenKeysByResponse ResponseGenKeysFunc // keys derived from the response, ignored BeforeInvocation
+	Condition         ConditionFunc       // skip the rule when false
+	BeforeInvocation  bool                // evict before the handler runs, e.g. deletes, default after
+	OnlyOnSuccess     bool                // evict after the handler only when the response status is 2xx
+	EvictTags         GenTagsFunc         // evict every entry with one of the tags
+}
+
 // Cacheable do caching
 type Cacheable struct {
-	GenKey     GenKeyFunc
+	GenKey     GenKeyFunc // nil the canonical key of method, route, path params and sorted query
 	CacheTime  time.Duration
 	OnCacheHit CacheHitHook // 命中缓存钩子 优先级最高, 可覆盖Caching的OnCacheHitting
+	// OnCacheHitBytes binary-safe OnCacheHit, takes priority over it
+	OnCacheHitBytes CacheHitBytesHook
+	// CacheHeaders response headers to store and replay on hit, overrides CacheHandler.CacheHeaders
+	CacheHeaders []string
+	// WaitTimeout how long concurrent misses wait for the request computing the key, default 5s, < 0 disable coalescing
+	WaitTimeout time.Duration
+	// StaleWhileRevalidate after CacheTime, serve the stale entry and refresh it in the background within this window
+	StaleWhileRevalidate time.Duration
+	// StaleIfError after CacheTime, serve the stale entry within this window when the handler fails with 5xx or c.Errors
+	StaleIfError time.Duration
+	// ShouldCache default caches 2xx not aborted and statuses in StatusCacheTime, both without c.Errors
+	ShouldCache ShouldCacheFunc
+	// StatusCacheTime cache time by response status, overrides CacheTime, e.g. {404: time.Second * 10}
+	StatusCacheTime map[int]time.Duration
+	// LastModified send the stored time as Last-Modified and answer If-Modified-Since, when the handler does not set it
+	LastModified bool
+	// Vary request headers appended to the key and sent as Vary, evict the variants with a trailing `*`
+	Vary []string
+	// Condition skip the Cacheable for the request when false, use ShouldCache to veto by the response
+	Condition ConditionFunc
+	// Tags attached to the entry, with the tags the handler adds by AddCacheTags. evict them with CacheEvictRule.EvictTags
+	Tags GenTagsFunc
+	// HashBody the canonical key without GenKey also has a hash of the json body, for POST queries
+	HashBody bool
 }
 
-// Caching mixins Cacheable and CacheEvict
+// CachePut always run the handler and write the response into the key, without lookup.
+// the lookup and hit fields of Cacheable are ignored
+type CachePut Cacheable
+
+// Caching mixins Cacheable, CacheEvict and CachePut
 type Caching struct {
-	Cacheable []Cacheable
-	Evict     []CacheEvict
+	Cacheable  []Cacheable
+	Evict      []CacheEvict
+	EvictRules []CacheEvictRule // evictions with predicates, run after Evict
+	Put        []CachePut       // written after the evictions, a Caching with Put skips the lookup of Cacheable
+}
+
+// RouteRule Caching of a route, matched by the method and the path it is registered with, e.g. GET /users/:id
+type RouteRule struct {
+	Method  string // "" matches every method
+	Path    string // c.FullPath(), the pattern of the route and not the request path
+	Caching Caching
 }
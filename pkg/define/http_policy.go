@@ -0,0 +1,12 @@
+package define
+
+// HTTPPolicy make the cache honor Cache-Control of the request and the response, each rule is opt-in
+type HTTPPolicy struct {
+	RequestNoCache bool // requests with Cache-Control: no-cache or Pragma: no-cache bypass the lookup
+	NoStore        bool // responses with no-store or private, or requests with no-store are not stored
+	SetCookie      bool // responses with Set-Cookie are not stored
+	MaxAge         bool // when CacheTime is zero, cache time is the s-maxage or max-age of the response
+}
+
+// StandardHTTPPolicy honor every rule of HTTPPolicy
+var StandardHTTPPolicy = HTTPPolicy{RequestNoCache: true, NoStore: true, SetCookie: true, MaxAge: true}
@@ -0,0 +1,209 @@
+package define
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingParam a param required by a KeyTemplate is missing or empty
+var ErrMissingParam = errors.New("gin-cache: missing key param")
+
+// params of each source, set by the params parser next to the merged params
+const (
+	PathParamsKey  = "gin-cache/path"
+	QueryParamsKey = "gin-cache/query"
+	BodyParamsKey  = "gin-cache/body"
+)
+
+// qualifiers of a param name, resolved in the params of their source only
+var keySources = map[string]string{"path": PathParamsKey, "query": QueryParamsKey, "body": BodyParamsKey}
+
+// KeyTemplate compiled key template, e.g. user:{id}:{query.page|default:1}
+//
+// {name} is a param, a dotted name reads a nested json object and may start with path., query. or body.
+// to read the param of that source only, a query param can not stand in for a path param.
+// filters: default:VALUE used when the param is missing or empty, lower, applied to the default too.
+// there is no upper, a key of GenKey is lowercased by the handler
+// {{ and }} are literal braces
+type KeyTemplate struct {
+	source string
+	parts  []keyPart
+}
+
+type keyPart struct {
+	literal  string
+	name     string
+	source   string
+	path     []string
+	fallback *string
+	filters  []func(string) string
+}
+
+// CompileKey parse template, syntax errors are returned at startup instead of bad keys at runtime
+func CompileKey(template string) (*KeyTemplate, error) {
+	t := &KeyTemplate{source: template}
+	var literal strings.Builder
+	for i := 0; i < len(template); i++ {
+		switch ch := template[i]; {
+		case ch == '{' && strings.HasPrefix(template[i:], "{{"), ch == '}' && strings.HasPrefix(template[i:], "}}"):
+			literal.WriteByte(ch)
+			i++
+		case ch == '}':
+			return nil, fmt.Errorf("gin-cache: key template %q: unexpected } at %d", template, i)
+		case ch == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("gin-cache: key template %q: unclosed { at %d", template, i)
+			}
+			part, err := compileParam(template[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("gin-cache: key template %q: %w", template, err)
+			}
+			if literal.Len() > 0 {
+				t.parts = append(t.parts, keyPart{literal: literal.String()})
+				literal.Reset()
+			}
+			t.parts = append(t.parts, part)
+			i += end
+		default:
+			literal.WriteByte(ch)
+		}
+	}
+	if literal.Len() > 0 {
+		t.parts = append(t.parts, keyPart{literal: literal.String()})
+	}
+	return t, nil
+}
+
+// MustCompileKey CompileKey which panics, for templates declared with the routes
+func MustCompileKey(template string) *KeyTemplate {
+	t, err := CompileKey(template)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func compileParam(expr string) (keyPart, error) {
+	segments := strings.Split(expr, "|")
+	name := strings.TrimSpace(segments[0])
+	if name == "" {
+		return keyPart{}, fmt.Errorf("empty param in {%s}", expr)
+	}
+	for _, ch := range name {
+		if !(ch == '_' || ch == '-' || ch == '.' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z') {
+			return keyPart{}, fmt.Errorf("invalid param name %q", name)
+		}
+	}
+
+	part := keyPart{name: name, path: strings.Split(name, ".")}
+	if source, ok := keySources[part.path[0]]; ok && len(part.path) > 1 {
+		part.source, part.path = source, part.path[1:]
+	}
+	for _, path := range part.path {
+		if path == "" {
+			return keyPart{}, fmt.Errorf("invalid param name %q", name)
+		}
+	}
+
+	for _, filter := range segments[1:] {
+		filter = strings.TrimSpace(filter)
+		switch {
+		case strings.HasPrefix(filter, "default:"):
+			fallback := strings.TrimPrefix(filter, "default:")
+			part.fallback = &fallback
+		case filter == "lower":
+			part.filters = append(part.filters, strings.ToLower)
+		default:
+			return keyPart{}, fmt.Errorf("unknown filter %q of %s", filter, name)
+		}
+	}
+	return part, nil
+}
+
+// Execute render the key, a required param which is missing fails with ErrMissingParam
+func (t *KeyTemplate) Execute(params map[string]interface{}) (string, error) {
+	var key strings.Builder
+	for _, part := range t.parts {
+		if part.name == "" {
+			key.WriteString(part.literal)
+			continue
+		}
+		value, err := part.value(params)
+		if err != nil {
+			return "", err
+		}
+		key.WriteString(value)
+	}
+	return key.String(), nil
+}
+
+// GenKey the template as GenKeyFunc, an error gives an empty key and the request is not cached
+func (t *KeyTemplate) GenKey() GenKeyFunc {
+	return func(params map[string]interface{}) string {
+		key, err := t.Execute(params)
+		if err != nil {
+			return ""
+		}
+		return key
+	}
+}
+
+func (t *KeyTemplate) String() string {
+	return t.source
+}
+
+func (part keyPart) value(params map[string]interface{}) (string, error) {
+	var value string
+	var err error
+	if part.source != "" {
+		source, _ := params[part.source].(map[string]interface{})
+		value, err = lookupParam(source, strings.Join(part.path, "."), part.path)
+	} else {
+		value, err = lookupParam(params, part.name, part.path)
+	}
+	if err == nil && value == "" {
+		err = fmt.Errorf("%w: %s", ErrMissingParam, part.name)
+	}
+	if err != nil {
+		if part.fallback == nil || !errors.Is(err, ErrMissingParam) {
+			return "", err
+		}
+		value = *part.fallback
+	}
+	for _, filter := range part.filters {
+		value = filter(value)
+	}
+	return value, nil
+}
+
+// lookupParam the flat name first, then the nested path
+func lookupParam(params map[string]interface{}, name string, path []string) (string, error) {
+	value, ok := params[name]
+	if !ok {
+		var current interface{} = params
+		for _, segment := range path {
+			object, isObject := current.(map[string]interface{})
+			if !isObject {
+				current = nil
+				break
+			}
+			current = object[segment]
+		}
+		value = current
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("%w: %s", ErrMissingParam, name)
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool, int, int64, int32, uint, uint64, uint32, fmt.Stringer:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("gin-cache: param %s is not a scalar", name)
+}
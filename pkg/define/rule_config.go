@@ -0,0 +1,16 @@
+package define
+
+// RuleConfig declarative form of RouteRule, read from yaml or json
+type RuleConfig struct {
+	Method string   `yaml:"method" json:"method"` // "" every method
+	Path   string   `yaml:"path" json:"path"`     // the pattern of the route, e.g. /users/:id
+	Key    string   `yaml:"key" json:"key"`       // KeyTemplate, e.g. user:{id}:{query.page|default:1}
+	TTL    string   `yaml:"ttl" json:"ttl"`       // time.ParseDuration, e.g. 10m, "" the driver default
+	Evict  []string `yaml:"evict" json:"evict"`   // key templates evicted after the handler, a trailing * matches the prefix
+	Vary   []string `yaml:"vary" json:"vary"`     // request headers appended to the key
+}
+
+// RulesConfig the root of a rule file
+type RulesConfig struct {
+	Rules []RuleConfig `yaml:"rules" json:"rules"`
+}
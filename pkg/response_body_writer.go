@@ -9,9 +9,34 @@ import (
 type ResponseBodyWriter struct {
 	gin.ResponseWriter
 	Body *bytes.Buffer
+	// Limit stop capturing once the body grows beyond it, the response still goes to the client. 0 no limit
+	Limit    int
+	exceeded bool
 }
 
-func (r ResponseBodyWriter) Write(b []byte) (int, error) {
-	r.Body.Write(b)
+func (r *ResponseBodyWriter) Write(b []byte) (int, error) {
+	r.capture(b)
 	return r.ResponseWriter.Write(b)
 }
+
+func (r *ResponseBodyWriter) WriteString(s string) (int, error) {
+	r.capture([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Exceeded the body grew beyond Limit, Body is dropped
+func (r *ResponseBodyWriter) Exceeded() bool {
+	return r.exceeded
+}
+
+func (r *ResponseBodyWriter) capture(b []byte) {
+	if r.exceeded {
+		return
+	}
+	if r.Limit > 0 && r.Body.Len()+len(b) > r.Limit {
+		r.exceeded = true
+		r.Body = bytes.NewBuffer(nil)
+		return
+	}
+	r.Body.Write(b)
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var binaryBody = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0x00, 0xff, 0xfe, 0xc3, 0x28}
+
+func Test_Binary_Body_Should_Round_Trip(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			var hooked []byte
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:image:%v", params["id"])
+			}
+			handler := func(c *gin.Context) {
+				c.Data(200, "image/png", binaryBody)
+			}
+			r.GET("/image", cache.Handler(define.Caching{
+				Cacheable: []define.Cacheable{{GenKey: genKey}},
+			}, handler))
+			r.GET("/image_hook", cache.Handler(define.Caching{
+				Cacheable: []define.Cacheable{{GenKey: genKey, OnCacheHitBytes: func(c *gin.Context, cacheValue []byte) {
+					hooked = cacheValue
+					c.Data(200, "image/png", cacheValue)
+				}}},
+			}, handler))
+
+			id := time.Now().UnixNano()
+			for _, path := range []string{"/image", "/image", "/image_hook"} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?id=%d", path, id), nil)
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+				assert.Equal(t, binaryBody, w.Body.Bytes())
+			}
+			assert.Equal(t, binaryBody, hooked)
+			assert.Equal(t, binaryBody, cache.LoadBytes(context.Background(), fmt.Sprintf("anson:image:%d", id)))
+
+			key := fmt.Sprintf("anson:raw:%d", id)
+			cache.SetBytes(context.Background(), key, binaryBody, time.Minute)
+			assert.Equal(t, binaryBody, cache.LoadBytes(context.Background(), key))
+			assert.Equal(t, string(binaryBody), cache.Load(context.Background(), key))
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Every_Cacheable_Should_Be_Stored_And_Looked_Up_In_Order(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/users", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:user:id:%v", params["id"])
+						}},
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:user:email:%v", params["email"])
+						}, CacheTime: time.Second},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.JSON(200, gin.H{"id": c.Query("id"), "email": c.Query("email")})
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users?id=%d&email=%d@anson", id, id), nil)
+				r.ServeHTTP(w, req)
+				assert.Equal(t, 200, w.Code)
+			}
+			idKey, emailKey := fmt.Sprintf("anson:user:id:%d", id), fmt.Sprintf("anson:user:email:%d@anson", id)
+
+			get()
+			expect := fmt.Sprintf(`{"id": "%d", "email": "%d@anson"}`, id, id)
+			for _, key := range []string{idKey, emailKey} {
+				equalJSON, err := AreEqualJSON(expect, cache.Load(context.Background(), key))
+				assert.True(t, equalJSON && err == nil, key)
+			}
+
+			// the second key is a hit when the first one is gone
+			cache.DoEvict(context.Background(), []string{idKey})
+			get()
+			assert.Equal(t, 1, invoked)
+
+			// each key keeps its own cache time
+			time.Sleep(time.Millisecond * 1200)
+			assert.Equal(t, "", cache.Load(context.Background(), emailKey))
+		})
+	}
+}
+
+func Test_Condition_Should_Skip_Cacheable_And_Evict(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:article:%v", params["id"])
+			}
+			r.GET("/articles", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: genKey, Condition: func(params map[string]interface{}, c *gin.Context) bool {
+							return params["debug"] == nil
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					c.JSON(200, gin.H{"id": c.Query("id")})
+				},
+			))
+			r.POST("/articles", cache.Handler(
+				define.Caching{
+					EvictRules: []define.CacheEvictRule{
+						{GenKey: genKey, Condition: func(params map[string]interface{}, c *gin.Context) bool {
+							return params["status"] == "published"
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					c.JSON(200, gin.H{"message": "saved"})
+				},
+			))
+
+			id := time.Now().UnixNano()
+			key := fmt.Sprintf("anson:article:%d", id)
+			serve := func(method, url, body string) {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, url, strings.NewReader(body))
+				r.ServeHTTP(w, req)
+				assert.Equal(t, 200, w.Code)
+			}
+
+			serve(http.MethodGet, fmt.Sprintf("/articles?id=%d&debug=1", id), "")
+			assert.Equal(t, "", cache.Load(context.Background(), key))
+
+			serve(http.MethodGet, fmt.Sprintf("/articles?id=%d", id), "")
+			assert.NotEqual(t, "", cache.Load(context.Background(), key))
+
+			serve(http.MethodPost, "/articles", fmt.Sprintf(`{"id": "%d", "status": "draft"}`, id))
+			assert.NotEqual(t, "", cache.Load(context.Background(), key))
+
+			serve(http.MethodPost, "/articles", fmt.Sprintf(`{"id": "%d", "status": "published"}`, id))
+			assert.Equal(t, "", cache.Load(context.Background(), key))
+		})
+	}
+}
+
+func Test_Evict_Before_Invocation_And_Only_On_Success(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:item:%v", params["id"])
+			}
+			r.GET("/items/:id", cache.Handler(
+				define.Caching{Cacheable: []define.Cacheable{{GenKey: genKey}}},
+				func(c *gin.Context) {
+					c.JSON(200, gin.H{"id": c.Param("id")})
+				},
+			))
+			r.PUT("/items/:id", cache.Handler(
+				define.Caching{EvictRules: []define.CacheEvictRule{{GenKey: genKey, OnlyOnSuccess: true}}},
+				func(c *gin.Context) {
+					status, _ := strconv.Atoi(c.Query("status"))
+					c.JSON(status, gin.H{})
+				},
+			))
+			var loadedInHandler string
+			r.DELETE("/items/:id", cache.Handler(
+				define.Caching{EvictRules: []define.CacheEvictRule{{GenKey: genKey, BeforeInvocation: true}}},
+				func(c *gin.Context) {
+					loadedInHandler = cache.Load(context.Background(), genKey(map[string]interface{}{"id": c.Param("id")}))
+					c.Status(http.StatusNoContent)
+				},
+			))
+
+			id := time.Now().UnixNano()
+			key := fmt.Sprintf("anson:item:%d", id)
+			serve := func(method string, status int) int {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, fmt.Sprintf("/items/%d?status=%d", id, status), nil)
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			serve(http.MethodGet, 0)
+			assert.NotEqual(t, "", cache.Load(context.Background(), key))
+
+			// a failed update keeps the cache warm
+			assert.Equal(t, http.StatusConflict, serve(http.MethodPut, http.StatusConflict))
+			assert.NotEqual(t, "", cache.Load(context.Background(), key))
+
+			assert.Equal(t, http.StatusOK, serve(http.MethodPut, http.StatusOK))
+			assert.Equal(t, "", cache.Load(context.Background(), key))
+
+			// the handler of a delete already sees the cache evicted
+			serve(http.MethodGet, 0)
+			assert.NotEqual(t, "", cache.Load(context.Background(), key))
+			serve(http.MethodDelete, 0)
+			assert.Equal(t, "", loadedInHandler)
+			assert.Equal(t, "", cache.Load(context.Background(), key))
+		})
+	}
+}
+
+func Test_Evict_Keys_From_Response(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			id := time.Now().UnixNano()
+			r.POST("/users", cache.Handler(
+				define.Caching{
+					EvictRules: []define.CacheEvictRule{
+						{GenKeysByResponse: func(params map[string]interface{}, response map[string]interface{}, body []byte) []string {
+							return []string{"anson:users:list:*", fmt.Sprintf("anson:users:id:%v", response["id"])}
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					c.JSON(http.StatusCreated, gin.H{"id": fmt.Sprintf("%d", id)})
+				},
+			))
+
+			keys := []string{
+				fmt.Sprintf("anson:users:list:%d:1", id),
+				fmt.Sprintf("anson:users:list:%d:2", id),
+				fmt.Sprintf("anson:users:id:%d", id),
+			}
+			for _, key := range keys {
+				cache.Set(context.Background(), key, "cached", time.Hour)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "anson"}`))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			for _, key := range keys {
+				assert.Equal(t, "", cache.Load(context.Background(), key), key)
+			}
+		})
+	}
+}
+
+func Test_Cache_Put_Should_Refresh_The_Cache(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			titles := map[string]string{}
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:article:id:%v", params["id"])
+			}
+			r.GET("/articles/:id", cache.Handler(
+				define.Caching{Cacheable: []define.Cacheable{{GenKey: genKey}}},
+				func(c *gin.Context) {
+					c.JSON(200, gin.H{"title": titles[c.Param("id")]})
+				},
+			))
+			r.PUT("/articles/:id", cache.Handler(
+				define.Caching{Put: []define.CachePut{{GenKey: genKey}}},
+				func(c *gin.Context) {
+					json := make(map[string]interface{})
+					_ = c.BindJSON(&json)
+					titles[c.Param("id")] = fmt.Sprintf("%v", json["title"])
+					c.JSON(200, gin.H{"title": titles[c.Param("id")]})
+				},
+			))
+
+			id := time.Now().UnixNano()
+			serve := func(method, body string) string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, fmt.Sprintf("/articles/%d", id), strings.NewReader(body))
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			serve(http.MethodPut, `{"title": "first"}`)
+			assert.Equal(t, `{"title":"first"}`, serve(http.MethodGet, ""))
+
+			// always executed, the cached representation is replaced
+			assert.Equal(t, `{"title":"second"}`, serve(http.MethodPut, `{"title": "second"}`))
+			assert.Equal(t, `{"title":"second"}`, cache.Load(context.Background(), fmt.Sprintf("anson:article:id:%d", id)))
+			assert.Equal(t, `{"title":"second"}`, serve(http.MethodGet, ""))
+		})
+	}
+}
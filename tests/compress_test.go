@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Compressed_Entry_Should_Be_Served_By_Accept_Encoding(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			cache.CompressMinSize = 256
+
+			invoked := 0
+			r.GET("/compress", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:compress:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, strings.Repeat(c.Query("word"), 64))
+				},
+			))
+
+			get := func(url, acceptEncoding string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			id := time.Now().UnixNano()
+			url := fmt.Sprintf("/compress?id=%d&word=anson,", id)
+			body := strings.Repeat("anson,", 64)
+
+			w := get(url, "gzip")
+			assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+			assert.Equal(t, body, w.Body.String())
+
+			w = get(url, "deflate, gzip;q=0.5")
+			assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+			reader, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+			require.NoError(t, err)
+			unzipped, _ := ioutil.ReadAll(reader)
+			assert.Equal(t, body, string(unzipped))
+
+			for _, acceptEncoding := range []string{"", "gzip;q=0", "br"} {
+				w = get(url, acceptEncoding)
+				assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+				assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+				assert.Equal(t, body, w.Body.String())
+			}
+			assert.Equal(t, 1, invoked)
+
+			// each representation carries its own validator
+			identityETag := get(url, "").Header().Get("ETag")
+			gzipETag := get(url, "gzip").Header().Get("ETag")
+			assert.NotEqual(t, "", identityETag)
+			assert.NotEqual(t, identityETag, gzipETag)
+			revalidate := func(etag, acceptEncoding string) int {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+				req.Header.Set("If-None-Match", etag)
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+			assert.Equal(t, http.StatusNotModified, revalidate(gzipETag, "gzip"))
+			assert.Equal(t, http.StatusOK, revalidate(gzipETag, ""))
+			assert.Equal(t, http.StatusNotModified, revalidate(identityETag, ""))
+			assert.Equal(t, http.StatusOK, revalidate(identityETag, "gzip"))
+
+			assert.Equal(t, body, cache.Load(context.Background(), fmt.Sprintf("anson:compress:%d", id)))
+
+			// below CompressMinSize stored raw
+			url = fmt.Sprintf("/compress?id=%d&word=a", id+1)
+			get(url, "gzip")
+			w = get(url, "gzip")
+			assert.Equal(t, "", w.Header().Get("Content-Encoding"))
+			assert.Equal(t, strings.Repeat("a", 64), w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Default_Key_Should_Be_Canonical(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := map[string]int{}
+			for _, route := range []string{"/auto/users/:id", "/auto/items/:id"} {
+				route := route
+				r.GET(route, cache.Handler(
+					define.Caching{Cacheable: []define.Cacheable{{}}},
+					func(c *gin.Context) {
+						invoked[route]++
+						c.String(200, "%s %s", route, c.Request.URL.RawQuery)
+					},
+				))
+			}
+
+			id := time.Now().UnixNano()
+			for _, url := range []string{
+				"/auto/users/%d?b=2&a=1",
+				"/auto/users/%d?a=1&b=2",
+				"/auto/users/%d?a=%%31&b=2",
+				"/auto/items/%d?a=1&b=2",
+				"/auto/items/%d?a=2&b=2",
+			} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf(url, id), nil)
+				r.ServeHTTP(w, req)
+				assert.Equal(t, 200, w.Code)
+			}
+
+			assert.Equal(t, map[string]int{"/auto/users/:id": 1, "/auto/items/:id": 2}, invoked)
+			key := fmt.Sprintf("get:/auto/users/:id|id=%d|a=1&b=2", id)
+			assert.Equal(t, "/auto/users/:id b=2&a=1", cache.Load(context.Background(), key))
+		})
+	}
+}
+
+func Test_Default_Key_Should_Keep_Case(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/auto/u/:name", cache.Handler(
+				define.Caching{Cacheable: []define.Cacheable{{}}},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "%s %s", c.Param("name"), c.Query("q"))
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func(url string) string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf(url, id), nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, fmt.Sprintf("alice%d x", id), get("/auto/u/alice%d?q=x"))
+			assert.Equal(t, fmt.Sprintf("Alice%d X", id), get("/auto/u/Alice%d?q=X"))
+			assert.Equal(t, fmt.Sprintf("alice%d x", id), get("/auto/u/alice%d?q=x"))
+			assert.Equal(t, 2, invoked)
+		})
+	}
+}
+
+func Test_Default_Key_Should_Hash_Json_Body(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.POST("/auto/search", cache.Handler(
+				define.Caching{Cacheable: []define.Cacheable{{HashBody: true}}},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "%d", invoked)
+				},
+			))
+
+			id := time.Now().UnixNano()
+			for _, body := range []string{
+				`{"id": %d, "tags": ["a", "b"]}`,
+				`{ "tags":["a","b"], "id":%d }`,
+				`{"id": %d, "tags": ["b", "a"]}`,
+			} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodPost, "/auto/search", strings.NewReader(fmt.Sprintf(body, id)))
+				r.ServeHTTP(w, req)
+				assert.Equal(t, 200, w.Code)
+			}
+			assert.Equal(t, 2, invoked)
+		})
+	}
+}
+
+func Test_Default_Key_Should_Be_Evicted_By_Pattern(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/auto/evict/:id", cache.Handler(
+				define.Caching{Cacheable: []define.Cacheable{{}}},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "%d", invoked)
+				},
+			))
+			r.POST("/auto/evict/:id", cache.Handler(
+				define.Caching{Evict: []define.CacheEvict{func(params map[string]interface{}) string {
+					return fmt.Sprintf("GET:/auto/evict/:id|id=%v|*", params["id"])
+				}}},
+				func(c *gin.Context) {},
+			))
+
+			id := time.Now().UnixNano()
+			serve := func(method string) string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, fmt.Sprintf("/auto/evict/%d?q=X", id), nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "1", serve(http.MethodGet))
+			assert.Equal(t, "1", serve(http.MethodGet))
+			serve(http.MethodPost)
+			assert.Equal(t, "2", serve(http.MethodGet))
+			assert.Equal(t, 2, invoked)
+		})
+	}
+}
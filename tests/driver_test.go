@@ -0,0 +1,267 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/pygzfei/gin-cache/cmd/startup"
+	"github.com/pygzfei/gin-cache/internal"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowCache never answers before the context is done
+type slowCache struct {
+	calls int32
+}
+
+func (s *slowCache) Load(ctx context.Context, key string) string {
+	atomic.AddInt32(&s.calls, 1)
+	<-ctx.Done()
+	return ""
+}
+
+func (s *slowCache) Set(ctx context.Context, key string, data string, timeout time.Duration) {
+	atomic.AddInt32(&s.calls, 1)
+	<-ctx.Done()
+}
+
+func (s *slowCache) DoEvict(ctx context.Context, keys []string) {
+	atomic.AddInt32(&s.calls, 1)
+	<-ctx.Done()
+}
+
+// flakyCache error-aware driver failing while down is set
+type flakyCache struct {
+	down  int32
+	calls int32
+}
+
+func (f *flakyCache) err() error {
+	atomic.AddInt32(&f.calls, 1)
+	if atomic.LoadInt32(&f.down) == 1 {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (f *flakyCache) Load(ctx context.Context, key string) (string, error) {
+	if err := f.err(); err != nil {
+		return "", err
+	}
+	return "", define.ErrCacheMiss
+}
+
+func (f *flakyCache) Set(ctx context.Context, key string, data string, timeout time.Duration) error {
+	return f.err()
+}
+
+func (f *flakyCache) DoEvict(ctx context.Context, keys []string) error {
+	return f.err()
+}
+
+func Test_Slow_Cache_Should_Fall_Through_After_Operation_Timeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver := &slowCache{}
+	cache := internal.New(internal.AdaptCache(driver), nil)
+	cache.OperationTimeout = time.Millisecond * 50
+
+	r := gin.New()
+	r.POST("/slow", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{
+				{GenKey: func(params map[string]interface{}) string {
+					return fmt.Sprintf("anson:slow:%v", params["id"])
+				}},
+			},
+			Evict: []define.CacheEvict{
+				func(params map[string]interface{}) string {
+					return "anson:slow:*"
+				},
+			},
+		},
+		func(c *gin.Context) {
+			c.String(200, "origin")
+		},
+	))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/slow", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "origin", w.Body.String())
+	// load, evict and set, each bounded by the timeout
+	assert.Equal(t, int32(3), atomic.LoadInt32(&driver.calls))
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func Test_Cache_Operations_Should_Stop_With_The_Request(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache := internal.New(internal.AdaptCache(&slowCache{}), nil)
+
+	r := gin.New()
+	r.GET("/slow", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{
+				{GenKey: func(params map[string]interface{}) string {
+					return "anson:slow"
+				}},
+			},
+		},
+		func(c *gin.Context) {
+			c.String(200, "origin")
+		},
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/slow", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "origin", w.Body.String())
+}
+
+func Test_Cache_Backend_Down_Should_Serve_From_Origin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache, _ := startup.RedisCache(time.Hour, &redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+
+	var mu sync.Mutex
+	failures := make(map[string][]string)
+	cache.OnCacheError = func(ctx context.Context, op string, keys []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.NotEqual(t, define.ErrCacheMiss, err)
+		failures[op] = append(failures[op], keys...)
+	}
+
+	invoked := 0
+	r := gin.New()
+	r.GET("/down", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{
+				{GenKey: func(params map[string]interface{}) string {
+					return "anson:down"
+				}},
+			},
+		},
+		func(c *gin.Context) {
+			invoked++
+			c.String(200, "origin")
+		},
+	))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/down", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, "origin", w.Body.String())
+	}
+	assert.Equal(t, 2, invoked)
+	assert.Equal(t, []string{"anson:down", "anson:down"}, failures[define.OpLoad])
+	assert.Equal(t, []string{"anson:down", "anson:down"}, failures[define.OpSet])
+}
+
+func Test_Miss_Should_Not_Be_Reported_As_Failure(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			var failures int32
+			cache.OnCacheError = func(ctx context.Context, op string, keys []string, err error) {
+				atomic.AddInt32(&failures, 1)
+			}
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/ping?id=%d", time.Now().UnixNano()), nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, "", cache.Load(context.Background(), "anson:missing"))
+			assert.Equal(t, int32(0), atomic.LoadInt32(&failures))
+		})
+	}
+}
+
+func Test_Circuit_Breaker_Should_Skip_The_Cache_While_Open(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver := &flakyCache{down: 1}
+	cache := internal.New(driver, nil)
+	breaker := startup.CircuitBreaker(cache)
+	breaker.FailureThreshold = 2
+	breaker.Cooldown = time.Millisecond * 100
+
+	var mu sync.Mutex
+	var transitions []string
+	breaker.OnStateChange = func(from, to define.BreakerState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, fmt.Sprintf("%v->%v", from, to))
+	}
+
+	r := gin.New()
+	r.GET("/breaker", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{
+				{GenKey: func(params map[string]interface{}) string {
+					return "anson:breaker"
+				}},
+			},
+		},
+		func(c *gin.Context) {
+			c.String(200, "origin")
+		},
+	))
+	get := func() {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/breaker", nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, "origin", w.Body.String())
+	}
+
+	// load and set fail, the second failure opens the breaker
+	get()
+	assert.Equal(t, define.BreakerOpen, breaker.State())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&driver.calls))
+
+	// open, the driver is not called
+	get()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&driver.calls))
+
+	// after the cooldown a successful trial closes it
+	time.Sleep(breaker.Cooldown)
+	assert.Equal(t, define.BreakerHalfOpen, breaker.State())
+	atomic.StoreInt32(&driver.down, 0)
+	get()
+	assert.Equal(t, define.BreakerClosed, breaker.State())
+	assert.Equal(t, int32(4), atomic.LoadInt32(&driver.calls))
+
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, transitions)
+}
+
+func Test_Circuit_Breaker_Should_Reopen_When_Trial_Fails(t *testing.T) {
+	driver := &flakyCache{down: 1}
+	breaker := internal.NewCircuitBreaker(driver)
+	breaker.FailureThreshold = 1
+	breaker.Cooldown = time.Millisecond * 50
+
+	_, err := breaker.Load(context.Background(), "anson:breaker")
+	assert.NotNil(t, err)
+	_, err = breaker.Load(context.Background(), "anson:breaker")
+	assert.Equal(t, define.ErrCircuitOpen, err)
+
+	time.Sleep(breaker.Cooldown)
+	assert.NotEqual(t, define.ErrCircuitOpen, breaker.Set(context.Background(), "anson:breaker", "v", 0))
+	assert.Equal(t, define.BreakerOpen, breaker.State())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&driver.calls))
+}
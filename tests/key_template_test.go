@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Key_Template_Should_Render_Params(t *testing.T) {
+	params := map[string]interface{}{
+		"id":   "7",
+		"page": "",
+		"name": "Anson",
+		"size": float64(1000000),
+		"user": map[string]interface{}{"id": float64(42), "tags": []interface{}{"a"}},
+		// the query overwrote the path param in the merged params
+		define.PathParamsKey:  map[string]interface{}{"id": "7"},
+		define.QueryParamsKey: map[string]interface{}{"id": "evil", "page": ""},
+		define.BodyParamsKey:  map[string]interface{}{"user": map[string]interface{}{"id": float64(42)}},
+	}
+
+	for _, item := range []struct {
+		template string
+		key      string
+		err      error
+	}{
+		{template: "user:{id}:{query.page|default:1}", key: "user:7:1"},
+		{template: "user:{path.id}:{name|lower}", key: "user:7:anson"},
+		{template: "user:{query.id}", key: "user:evil"},
+		{template: "size:{size}", key: "size:1000000"},
+		{template: "owner:{body.user.id}", key: "owner:42"},
+		{template: "owner:{user.id}", key: "owner:42"},
+		{template: "user:{path.name}", err: define.ErrMissingParam},
+		{template: "users:{{list}}:*", key: "users:{list}:*"},
+		{template: "user:{missing|default:NONE|lower}", key: "user:none"},
+		{template: "user:{missing}", err: define.ErrMissingParam},
+		{template: "user:{page}", err: define.ErrMissingParam},
+	} {
+		key, err := define.MustCompileKey(item.template).Execute(params)
+		assert.Equal(t, item.key, key, item.template)
+		assert.True(t, errors.Is(err, item.err), item.template)
+	}
+
+	_, err := define.MustCompileKey("tags:{user.tags}").Execute(params)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", define.MustCompileKey("user:{missing}").GenKey()(params))
+
+	for _, template := range []string{"user:{id", "user:id}", "user:{}", "user:{id|trim}", "user:{a..b}", "user:{i d}", "user:{path.}", "user:{id|upper}"} {
+		_, err := define.CompileKey(template)
+		assert.NotNil(t, err, template)
+	}
+	assert.Panics(t, func() { define.MustCompileKey("user:{") })
+}
+
+func Test_Key_Template_Should_Gen_Cache_Keys(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/template/:id", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: define.MustCompileKey("anson:template:{path.id}:{query.page|default:1}").GenKey()},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "page %s", c.DefaultQuery("page", "1"))
+				},
+			))
+
+			id := time.Now().UnixNano()
+			// a query param id can not take the place of the path param
+			for _, query := range []string{"", "?page=1", "?page=2", "?page=2&id=poison"} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/template/%d%s", id, query), nil)
+				r.ServeHTTP(w, req)
+			}
+			assert.Equal(t, 2, invoked)
+			assert.Equal(t, "page 1", cache.Load(context.Background(), fmt.Sprintf("anson:template:%d:1", id)))
+			assert.Equal(t, "page 2", cache.Load(context.Background(), fmt.Sprintf("anson:template:%d:2", id)))
+			assert.Equal(t, "", cache.Load(context.Background(), "anson:template:poison:2"))
+		})
+	}
+}
+
+func Test_Key_Template_Keys_Should_Be_Stored_Lowercase(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			r.GET("/template_case/:name", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: define.MustCompileKey("anson:case:{path.name}:{query.tag|default:NONE}").GenKey()},
+					},
+				},
+				func(c *gin.Context) {
+					c.String(200, c.Param("name"))
+				},
+			))
+
+			name := fmt.Sprintf("AB%d", time.Now().UnixNano())
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/template_case/"+name, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, name, cache.Load(context.Background(), fmt.Sprintf("anson:case:%s:none", strings.ToLower(name))))
+			assert.Equal(t, "", cache.Load(context.Background(), fmt.Sprintf("anson:case:%s:NONE", name)))
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Oversized_Request_Should_Stream_Through(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			cache.MaxRequestBodySize = 64
+
+			id := time.Now().UnixNano()
+			var evicted []interface{}
+			invoked := 0
+			r.POST("/upload", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:upload:%v", params["id"])
+						}},
+					},
+					Evict: []define.CacheEvict{
+						func(params map[string]interface{}) string {
+							evicted = append(evicted, params["id"])
+							return fmt.Sprintf("anson:upload_list:%d", id)
+						},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					body, _ := ioutil.ReadAll(c.Request.Body)
+					c.String(200, "%d", len(body))
+				},
+			))
+
+			for _, item := range []struct {
+				body          string
+				contentLength bool
+				cached        bool
+			}{
+				{body: fmt.Sprintf(`{"id": %d}`, id), contentLength: true, cached: true},
+				{body: fmt.Sprintf(`{"id": %d, "data": "%s"}`, id+1, strings.Repeat("x", 128)), contentLength: true, cached: false},
+				// chunked, the size is only known while reading
+				{body: fmt.Sprintf(`{"id": %d, "data": "%s"}`, id+2, strings.Repeat("x", 128)), contentLength: false, cached: false},
+			} {
+				evicted = nil
+				for i := 0; i < 2; i++ {
+					w := httptest.NewRecorder()
+					req, _ := http.NewRequest(http.MethodPost, "/upload", ioutil.NopCloser(strings.NewReader(item.body)))
+					if item.contentLength {
+						req.ContentLength = int64(len(item.body))
+					}
+					r.ServeHTTP(w, req)
+					assert.Equal(t, fmt.Sprintf("%d", len(item.body)), w.Body.String())
+				}
+				if item.cached {
+					assert.Equal(t, 1, invoked)
+					assert.NotNil(t, evicted[0])
+				} else {
+					// the body is not parsed, the evictions still run
+					assert.Equal(t, []interface{}{nil, nil}, evicted)
+				}
+				invoked = 0
+			}
+		})
+	}
+}
+
+func Test_Large_Response_Should_Not_Be_Cached(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			cache.MaxResponseBodySize = 1024
+
+			r.GET("/download", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:download:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					size := 16
+					if c.Query("large") != "" {
+						size = 4096
+					}
+					for i := 0; i < size/16; i++ {
+						_, _ = c.Writer.WriteString(strings.Repeat("x", 16))
+					}
+				},
+			))
+
+			id := time.Now().UnixNano()
+			for _, item := range []struct {
+				query  string
+				size   int
+				cached bool
+			}{
+				{query: "", size: 16, cached: true},
+				{query: "&large=1", size: 4096, cached: false},
+			} {
+				id++
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/download?id=%d%s", id, item.query), nil)
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, item.size, w.Body.Len())
+				load := cache.Load(context.Background(), fmt.Sprintf("anson:download:%d", id))
+				assert.Equal(t, item.cached, load != "")
+			}
+		})
+	}
+}
+
+func Test_Large_Response_Should_Stream_Instead_Of_Stale_If_Error(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			cache.MaxResponseBodySize = 64
+
+			var w *httptest.ResponseRecorder
+			streamed := 0
+			r.GET("/download_sie", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:download_sie:%v", params["id"])
+						}, CacheTime: time.Millisecond * 100, StaleIfError: time.Hour},
+					},
+				},
+				func(c *gin.Context) {
+					if c.Query("large") == "" {
+						c.String(200, "fresh")
+						return
+					}
+					c.Status(http.StatusInternalServerError)
+					for i := 0; i < 4096/16; i++ {
+						_, _ = c.Writer.WriteString(strings.Repeat("x", 16))
+					}
+					// already sent, not held in memory for the fallback
+					streamed = w.Body.Len()
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func(query string) {
+				w = httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/download_sie?id=%d%s", id, query), nil)
+				r.ServeHTTP(w, req)
+			}
+
+			get("")
+			assert.Equal(t, "fresh", w.Body.String())
+			time.Sleep(time.Millisecond * 150)
+
+			get("&large=1")
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+			assert.Equal(t, 4096, w.Body.Len())
+			assert.True(t, streamed > 0)
+			assert.Equal(t, "fresh", cache.Load(context.Background(), fmt.Sprintf("anson:download_sie:%d", id)))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Middleware_Should_Apply_Route_Rules(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			id := time.Now().UnixNano()
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:mw:%d:%v", id, params["id"])
+			}
+			rules := []define.RouteRule{
+				{Method: http.MethodGet, Path: "/mw/users/:id", Caching: define.Caching{
+					Cacheable: []define.Cacheable{{GenKey: genKey}},
+				}},
+				{Path: "/mw/users/:id/touch", Caching: define.Caching{
+					Evict: []define.CacheEvict{func(params map[string]interface{}) string {
+						return genKey(params)
+					}},
+				}},
+			}
+
+			invoked, after := 0, 0
+			group := r.Group("/mw", cache.Middleware(rules), func(c *gin.Context) {
+				after++
+			})
+			group.GET("/users/:id", func(c *gin.Context) {
+				invoked++
+				c.String(200, "user %s %d", c.Param("id"), invoked)
+			})
+			group.POST("/users/:id/touch", func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+			group.GET("/other", func(c *gin.Context) {
+				invoked++
+				c.String(200, "other")
+			})
+
+			serve := func(method, url string) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, url, nil)
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			assert.Equal(t, "user 1 1", serve(http.MethodGet, "/mw/users/1").Body.String())
+			// a hit aborts the chain
+			assert.Equal(t, "user 1 1", serve(http.MethodGet, "/mw/users/1").Body.String())
+			assert.Equal(t, 1, after)
+			assert.Equal(t, "user 1 1", cache.Load(context.Background(), fmt.Sprintf("anson:mw:%d:1", id)))
+
+			assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "/mw/users/1/touch").Code)
+			assert.Equal(t, "", cache.Load(context.Background(), fmt.Sprintf("anson:mw:%d:1", id)))
+			assert.Equal(t, "user 1 2", serve(http.MethodGet, "/mw/users/1").Body.String())
+
+			// without a rule
+			serve(http.MethodGet, "/mw/other")
+			serve(http.MethodGet, "/mw/other")
+			assert.Equal(t, 4, invoked)
+		})
+	}
+}
+
+func Test_Middleware_Should_Revalidate_With_The_Route_Handler(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			key := fmt.Sprintf("anson:mw_stale:%d", time.Now().UnixNano())
+			r.Use(cache.Middleware([]define.RouteRule{
+				{Method: http.MethodGet, Path: "/mw_stale", Caching: define.Caching{
+					Cacheable: []define.Cacheable{{
+						GenKey:               func(params map[string]interface{}) string { return key },
+						CacheTime:            time.Millisecond * 100,
+						StaleWhileRevalidate: time.Minute,
+					}},
+				}},
+			}))
+			var version int32
+			r.GET("/mw_stale", func(c *gin.Context) {
+				c.String(200, "v%d", atomic.AddInt32(&version, 1))
+			})
+
+			get := func() string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/mw_stale", nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "v1", get())
+			time.Sleep(time.Millisecond * 150)
+			assert.Equal(t, "v1", get())
+			assert.Eventually(t, func() bool {
+				return cache.Load(context.Background(), key) == "v2"
+			}, time.Second*2, time.Millisecond*20)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Key_Namespace_Should_Prefix_Driver_Keys(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			prefix := fmt.Sprintf("svc%d", time.Now().UnixNano())
+			cache.KeyPrefix = prefix
+			cache.Generation = 1
+
+			invoked := 0
+			r.GET("/ns", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:ns:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "%d", invoked)
+				},
+			))
+			r.POST("/ns_evict", cache.Handler(
+				define.Caching{
+					Evict: []define.CacheEvict{func(params map[string]interface{}) string {
+						return "anson:ns:*"
+					}},
+				},
+				func(c *gin.Context) {},
+			))
+			serve := func(method, url string) string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, url, nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "1", serve(http.MethodGet, "/ns?id=1"))
+			assert.Equal(t, "1", serve(http.MethodGet, "/ns?id=1"))
+			assert.Equal(t, "1", cache.Load(context.Background(), "anson:ns:1"))
+			_, err := cache.Cache.Load(context.Background(), "anson:ns:1")
+			assert.Equal(t, define.ErrCacheMiss, err)
+			_, err = cache.Cache.Load(context.Background(), prefix+":v1:anson:ns:1")
+			assert.Nil(t, err)
+
+			// a new generation does not see the old entries
+			cache.Generation = 2
+			assert.Equal(t, "2", serve(http.MethodGet, "/ns?id=1"))
+			assert.Equal(t, "2", serve(http.MethodGet, "/ns?id=1"))
+
+			// the pattern only matches keys of the namespace
+			serve(http.MethodPost, "/ns_evict")
+			assert.Equal(t, "", cache.Load(context.Background(), "anson:ns:1"))
+			_, err = cache.Cache.Load(context.Background(), prefix+":v1:anson:ns:1")
+			assert.Nil(t, err)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/internal"
+	"github.com/pygzfei/gin-cache/internal/drivers/memcache"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_HTTP_Policy_Should_Honor_Cache_Control(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			policy := define.StandardHTTPPolicy
+			cache.HTTPPolicy = &policy
+
+			var version int32
+			r.GET("/policy", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:policy:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					switch c.Query("mode") {
+					case "no-store", "private", "max-age=1":
+						c.Header("Cache-Control", c.Query("mode"))
+					case "cookie":
+						c.SetCookie("session", "1", 0, "/", "", false, true)
+					}
+					c.String(200, "v%d", atomic.AddInt32(&version, 1))
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func(query string, header http.Header) string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/policy?id=%d&%s", id, query), nil)
+				for name, values := range header {
+					req.Header[name] = values
+				}
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+			load := func() string {
+				return cache.Load(context.Background(), fmt.Sprintf("anson:policy:%d", id))
+			}
+
+			for _, mode := range []string{"no-store", "private", "cookie"} {
+				get("mode="+mode, nil)
+				assert.Equal(t, "", load(), mode)
+			}
+
+			// the request no-cache skips the cached v4 and stores the fresh v5
+			assert.Equal(t, "v4", get("", nil))
+			assert.Equal(t, "v4", get("", nil))
+			assert.Equal(t, "v5", get("", http.Header{"Cache-Control": []string{"no-cache"}}))
+			assert.Equal(t, "v5", load())
+
+			id++
+			get("mode=max-age=1", nil)
+			assert.Equal(t, "v6", load())
+			time.Sleep(time.Millisecond * 1200)
+			assert.Equal(t, "", load())
+		})
+	}
+}
+
+func Test_HTTP_Policy_Passed_To_New(t *testing.T) {
+	policy := define.HTTPPolicy{SetCookie: true}
+	cache := internal.New(memcache.NewMemoryHandler(), &policy)
+	assert.Equal(t, &policy, cache.HTTPPolicy)
+	assert.Nil(t, internal.New(memcache.NewMemoryHandler(), nil).HTTPPolicy)
+}
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_Cache_Hit_Should_Replay_Status_And_Headers(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/report", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:report:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.Header("Content-Disposition", `attachment; filename="report.csv"`)
+					c.Header("X-Request-Id", fmt.Sprintf("%d", invoked))
+					c.Data(http.StatusCreated, "text/csv", []byte("id,name\n1,anson\n"))
+				},
+			))
+
+			url := fmt.Sprintf("/report?id=%d", time.Now().UnixNano())
+			var responses []*httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				r.ServeHTTP(w, req)
+				responses = append(responses, w)
+			}
+
+			assert.Equal(t, 1, invoked)
+			for _, w := range responses {
+				assert.Equal(t, http.StatusCreated, w.Code)
+				assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="report.csv"`, w.Header().Get("Content-Disposition"))
+				assert.Equal(t, "id,name\n1,anson\n", w.Body.String())
+			}
+			// not in the whitelist, only the origin response carries it
+			assert.Equal(t, "1", responses[0].Header().Get("X-Request-Id"))
+			assert.Equal(t, "", responses[1].Header().Get("X-Request-Id"))
+		})
+	}
+}
+
+func Test_Error_Response_Should_Not_Be_Cached(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			genKey := func(params map[string]interface{}) string {
+				return fmt.Sprintf("anson:status:%v:%v", params["status"], params["id"])
+			}
+			handler := func(c *gin.Context) {
+				status, _ := strconv.Atoi(c.Query("status"))
+				if c.Query("abort") != "" {
+					c.AbortWithStatusJSON(status, gin.H{"status": status})
+					return
+				}
+				c.String(status, "status %d", status)
+			}
+			r.GET("/status", cache.Handler(define.Caching{
+				Cacheable: []define.Cacheable{
+					{GenKey: genKey, StatusCacheTime: map[int]time.Duration{http.StatusNotFound: time.Minute}},
+				},
+			}, handler))
+			r.GET("/status_custom", cache.Handler(define.Caching{
+				Cacheable: []define.Cacheable{
+					{GenKey: genKey, ShouldCache: func(c *gin.Context, status int, body []byte) bool {
+						return status == http.StatusInternalServerError
+					}},
+				},
+			}, handler))
+
+			id := time.Now().UnixNano()
+			for _, item := range []struct {
+				url    string
+				status int
+				cached bool
+			}{
+				{url: "/status?status=200", status: 200, cached: true},
+				{url: "/status?status=500", status: 500, cached: false},
+				{url: "/status?status=400", status: 400, cached: false},
+				{url: "/status?status=201&abort=1", status: 201, cached: false},
+				{url: "/status?status=404", status: 404, cached: true},
+				{url: "/status_custom?status=200", status: 200, cached: false},
+				{url: "/status_custom?status=500", status: 500, cached: true},
+			} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("%s&id=%d", item.url, id), nil)
+				r.ServeHTTP(w, req)
+				id++
+
+				loadCache := cache.Load(context.Background(), fmt.Sprintf("anson:status:%d:%d", item.status, id-1))
+				assert.Equal(t, item.status, w.Code, item.url)
+				assert.Equal(t, item.cached, loadCache != "", item.url)
+			}
+		})
+	}
+}
+
+func Test_Cache_Hit_Should_Answer_Not_Modified(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			r.GET("/etag", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:etag:%v", params["id"])
+						}, LastModified: true},
+					},
+				},
+				func(c *gin.Context) {
+					c.JSON(200, gin.H{"id": c.Query("id")})
+				},
+			))
+
+			url := fmt.Sprintf("/etag?id=%d", time.Now().UnixNano())
+			get := func(header http.Header) *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, url, nil)
+				for name, values := range header {
+					req.Header[name] = values
+				}
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			get(nil)
+			w := get(nil)
+			etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+			assert.NotEqual(t, "", etag)
+			assert.NotEqual(t, "", lastModified)
+
+			for _, header := range []http.Header{
+				{"If-None-Match": []string{etag}},
+				{"If-None-Match": []string{`"other", ` + etag}},
+				{"If-Modified-Since": []string{lastModified}},
+			} {
+				w = get(header)
+				assert.Equal(t, http.StatusNotModified, w.Code)
+				assert.Equal(t, "", w.Body.String())
+				assert.Equal(t, etag, w.Header().Get("ETag"))
+			}
+
+			w = get(http.Header{"If-None-Match": []string{`"other"`}, "If-Modified-Since": []string{lastModified}})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NotEqual(t, "", w.Body.String())
+		})
+	}
+}
+
+func Test_Vary_Headers_Should_Split_Cache_Keys(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			invoked := 0
+			r.GET("/vary", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:vary:%v", params["id"])
+						}, Vary: []string{"Accept-Language"}},
+					},
+				},
+				func(c *gin.Context) {
+					invoked++
+					c.String(200, "hello %s", c.GetHeader("Accept-Language"))
+				},
+			))
+
+			id := time.Now().UnixNano()
+			for _, language := range []string{"en", "zh", "EN", "zh"} {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/vary?id=%d", id), nil)
+				req.Header.Set("Accept-Language", language)
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+			}
+			assert.Equal(t, 2, invoked)
+			assert.Equal(t, "hello en", cache.Load(context.Background(), fmt.Sprintf("anson:vary:%d|accept-language=en", id)))
+			assert.Equal(t, "hello zh", cache.Load(context.Background(), fmt.Sprintf("anson:vary:%d|accept-language=zh", id)))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/cmd/startup"
+	"github.com/pygzfei/gin-cache/internal"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Parse_Rules_Should_Build_Caching(t *testing.T) {
+	yamlRules := `
+rules:
+  - method: GET
+    path: /users/:id
+    key: "user:{id}"
+    ttl: 10m
+    vary: [Accept-Language]
+  - method: PUT
+    path: /users/:id
+    evict: ["user:{id}*", "users:list:*"]
+`
+	jsonRules := `{"rules": [
+  {"method": "GET", "path": "/users/:id", "key": "user:{id}", "ttl": "10m", "vary": ["Accept-Language"]},
+  {"method": "PUT", "path": "/users/:id", "evict": ["user:{id}*", "users:list:*"]}
+]}`
+
+	for name, data := range map[string]string{"rules.yaml": yamlRules, "rules.json": jsonRules} {
+		rules, err := internal.ParseRules(name, []byte(data))
+		assert.Nil(t, err, name)
+		assert.Equal(t, 2, len(rules), name)
+
+		get := rules[0].Caching.Cacheable[0]
+		assert.Equal(t, "/users/:id", rules[0].Path)
+		assert.Equal(t, time.Minute*10, get.CacheTime)
+		assert.Equal(t, []string{"Accept-Language"}, get.Vary)
+		assert.Equal(t, "user:7", get.GenKey(map[string]interface{}{"id": "7"}))
+		assert.Equal(t, "", get.GenKey(map[string]interface{}{}))
+
+		put := rules[1].Caching
+		assert.Equal(t, 0, len(put.Cacheable))
+		assert.Equal(t, "user:7*", put.Evict[0](map[string]interface{}{"id": 7}))
+		assert.Equal(t, "users:list:*", put.Evict[1](map[string]interface{}{"id": 7}))
+	}
+
+	for _, data := range []string{
+		"rules:\n  - {path: /users/:id, key: 'user:{id}', ttl: ten}",
+		"rules:\n  - {key: 'user:{id}'}",
+		"rules:\n  - {path: /users/:id}",
+		"rules:\n  - {path: /users/:id, key: 'user:{id}', tll: 10m}",
+		"rules:\n  - {path: /users/:id, key: 'user:{id|trim}'}",
+		"rules:\n  - {path: /users/:id, evict: ['user:{id']}",
+	} {
+		_, err := internal.ParseRules("rules.yml", []byte(data))
+		assert.NotNil(t, err, data)
+	}
+	for _, data := range []string{
+		`{"rules": [{"path": "/users/:id", "key": "user:{id}", "tll": "10m"}]}`,
+		`{"rules": [{"path": "/users/:id", "key": "user:{id}", "ttl": "ten"}]}`,
+		`{"rules": [{"path": "/users/:id", "key": "user:{id}"}]`,
+	} {
+		_, err := internal.ParseRules("rules.json", []byte(data))
+		assert.NotNil(t, err, data)
+	}
+}
+
+func Test_Rule_File_Should_Reload_While_Serving(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			dir, _ := ioutil.TempDir("", "gin-cache")
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "rules.yaml")
+			id := time.Now().UnixNano()
+			write := func(rules string, modTime time.Time) {
+				_ = ioutil.WriteFile(path, []byte(rules), 0644)
+				_ = os.Chtimes(path, modTime, modTime)
+			}
+			write(fmt.Sprintf("rules:\n  - {method: GET, path: /rule_file/:id, key: 'anson:rule_file:%d:{id}'}\n", id), time.Now().Add(-time.Minute))
+
+			file, err := startup.LoadRuleFile(path)
+			assert.Nil(t, err)
+			reloadErrors := make(chan error, 1)
+			file.OnReloadError = func(err error) {
+				reloadErrors <- err
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go file.Watch(ctx, time.Millisecond*20)
+
+			invoked := 0
+			group := r.Group("/rule_file", cache.FileMiddleware(file))
+			group.GET("/:id", func(c *gin.Context) {
+				invoked++
+				c.String(200, "%d", invoked)
+			})
+			get := func() string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/rule_file/1", nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "1", get())
+			assert.Equal(t, "1", get())
+			assert.Equal(t, "1", cache.Load(context.Background(), fmt.Sprintf("anson:rule_file:%d:1", id)))
+
+			// the new key is used once the watcher picked up the file
+			write(fmt.Sprintf("rules:\n  - {method: GET, path: /rule_file/:id, key: 'anson:rule_file_v2:%d:{id}'}\n", id), time.Now())
+			time.Sleep(time.Millisecond * 100)
+			assert.Equal(t, "2", get())
+			assert.Equal(t, "2", get())
+			assert.Equal(t, "2", cache.Load(context.Background(), fmt.Sprintf("anson:rule_file_v2:%d:1", id)))
+
+			// a broken file is reported, the rules in use stay
+			write("rules:\n  - {method: GET, path: /rule_file/:id, key: 'x', ttl: soon}\n", time.Now().Add(time.Minute))
+			select {
+			case err := <-reloadErrors:
+				assert.NotNil(t, err)
+			case <-time.After(time.Second):
+				t.Error("reload error is not reported")
+			}
+			assert.Equal(t, "2", get())
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serveConcurrently(r *gin.Engine, url string, n int) []*httptest.ResponseRecorder {
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, url, nil)
+			r.ServeHTTP(w, req)
+			responses[i] = w
+		}(i)
+	}
+	wg.Wait()
+	return responses
+}
+
+func Test_Concurrent_Miss_Should_Be_Coalesced(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			var invoked int32
+			r.GET("/hot", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:hot:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					atomic.AddInt32(&invoked, 1)
+					time.Sleep(time.Millisecond * 200)
+					c.JSON(200, gin.H{"id": c.Query("id")})
+				},
+			))
+
+			id := time.Now().UnixNano()
+			etags := map[string]int{}
+			for _, w := range serveConcurrently(r, fmt.Sprintf("/hot?id=%d", id), 10) {
+				assert.Equal(t, 200, w.Code)
+				equalJSON, err := AreEqualJSON(fmt.Sprintf(`{"id": "%d"}`, id), w.Body.String())
+				assert.True(t, equalJSON && err == nil)
+				etags[w.Header().Get("ETag")]++
+			}
+			assert.Equal(t, int32(1), atomic.LoadInt32(&invoked))
+
+			// the waiters get the stored entry, validators included, only the leader has none
+			assert.Equal(t, 1, etags[""])
+			assert.Equal(t, 2, len(etags))
+		})
+	}
+}
+
+func Test_Coalesced_Miss_Should_Recover_When_Leader_Panics(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			var invoked int32
+			r.GET("/hot_panic", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:hot_panic:%v", params["id"])
+						}},
+					},
+				},
+				func(c *gin.Context) {
+					if atomic.AddInt32(&invoked, 1) == 1 {
+						time.Sleep(time.Millisecond * 200)
+						panic("leader failed")
+					}
+					c.JSON(200, gin.H{"id": c.Query("id")})
+				},
+			))
+
+			ok := 0
+			for _, w := range serveConcurrently(r, fmt.Sprintf("/hot_panic?id=%d", time.Now().UnixNano()), 5) {
+				if w.Code == http.StatusOK {
+					ok++
+				}
+			}
+			assert.Equal(t, 4, ok)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Stale_While_Revalidate_Should_Serve_Stale_And_Refresh(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			var version int32
+			r.GET("/swr", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:swr:%v", params["id"])
+						}, CacheTime: time.Second, StaleWhileRevalidate: time.Hour},
+					},
+				},
+				func(c *gin.Context) {
+					c.String(200, "v%d", atomic.AddInt32(&version, 1))
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func() string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/swr?id=%d", id), nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "v1", get())
+			time.Sleep(time.Millisecond * 1200)
+
+			// expired, the stale value is served right away
+			assert.Equal(t, "v1", get())
+			assert.Eventually(t, func() bool {
+				return cache.Load(context.Background(), fmt.Sprintf("anson:swr:%d", id)) == "v2"
+			}, time.Second*2, time.Millisecond*20)
+			assert.Equal(t, "v2", get())
+			assert.Equal(t, int32(2), atomic.LoadInt32(&version))
+		})
+	}
+}
+
+func Test_Stale_While_Revalidate_Should_Start_After_Driver_Default_Timeout(t *testing.T) {
+	r, cache := givingCacheOfHttpServer(time.Millisecond*100, RedisCache)
+
+	var version int32
+	r.GET("/swr_default", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{
+				{GenKey: func(params map[string]interface{}) string {
+					return fmt.Sprintf("anson:swr_default:%v", params["id"])
+				}, StaleWhileRevalidate: time.Hour},
+			},
+		},
+		func(c *gin.Context) {
+			c.String(200, "v%d", atomic.AddInt32(&version, 1))
+		},
+	))
+
+	id := time.Now().UnixNano()
+	get := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/swr_default?id=%d", id), nil)
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "v1", get())
+	time.Sleep(time.Millisecond * 200)
+
+	// past the default timeout of the driver the entry is stale, not gone
+	assert.Equal(t, "v1", get())
+	assert.Eventually(t, func() bool {
+		return cache.Load(context.Background(), fmt.Sprintf("anson:swr_default:%d", id)) == "v2"
+	}, time.Second*2, time.Millisecond*20)
+}
+
+func Test_Stale_If_Error_Should_Serve_Stale_When_Handler_Fails(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			var failing int32
+			r.GET("/sie", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:sie:%v", params["id"])
+						}, CacheTime: time.Second, StaleIfError: time.Hour},
+					},
+				},
+				func(c *gin.Context) {
+					switch atomic.LoadInt32(&failing) {
+					case 1:
+						c.String(http.StatusBadGateway, "origin down")
+					case 2:
+						_ = c.Error(errors.New("origin down"))
+						c.String(200, "partial")
+					case 3:
+						c.String(200, "recovered")
+					default:
+						c.String(200, "fresh")
+					}
+				},
+			))
+
+			id := time.Now().UnixNano()
+			get := func() *httptest.ResponseRecorder {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/sie?id=%d", id), nil)
+				r.ServeHTTP(w, req)
+				return w
+			}
+
+			assert.Equal(t, "fresh", get().Body.String())
+			time.Sleep(time.Millisecond * 1200)
+
+			atomic.StoreInt32(&failing, 1)
+			w := get()
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, "fresh", w.Body.String())
+
+			atomic.StoreInt32(&failing, 2)
+			assert.Equal(t, "fresh", get().Body.String())
+
+			// a successful handler replaces the stale entry
+			atomic.StoreInt32(&failing, 3)
+			assert.Equal(t, "recovered", get().Body.String())
+			assert.Equal(t, "recovered", cache.Load(context.Background(), fmt.Sprintf("anson:sie:%d", id)))
+		})
+	}
+}
+
+func Test_Stale_If_Error_Fallback_Should_Not_Count_As_Success(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			id := time.Now().UnixNano()
+			other := fmt.Sprintf("anson:sie_other:%d", id)
+			var failing int32
+			r.GET("/sie_evict", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:sie_evict:%v", params["id"])
+						}, CacheTime: time.Millisecond * 100, StaleIfError: time.Hour},
+					},
+					EvictRules: []define.CacheEvictRule{
+						{GenKey: func(params map[string]interface{}) string { return other }, OnlyOnSuccess: true},
+					},
+				},
+				func(c *gin.Context) {
+					if atomic.LoadInt32(&failing) == 1 {
+						c.String(http.StatusBadGateway, "origin down")
+						return
+					}
+					c.String(200, "fresh")
+				},
+			))
+			get := func() string {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/sie_evict?id=%d", id), nil)
+				r.ServeHTTP(w, req)
+				return w.Body.String()
+			}
+
+			assert.Equal(t, "fresh", get())
+			cache.Set(context.Background(), other, "other", time.Hour)
+			time.Sleep(time.Millisecond * 150)
+
+			// the stale 200 is sent, the handler failed
+			atomic.StoreInt32(&failing, 1)
+			assert.Equal(t, "fresh", get())
+			assert.Equal(t, "other", cache.Load(context.Background(), other))
+
+			atomic.StoreInt32(&failing, 0)
+			time.Sleep(time.Millisecond * 150)
+			assert.Equal(t, "fresh", get())
+			assert.Equal(t, "", cache.Load(context.Background(), other))
+		})
+	}
+}
+
+func Test_Revalidate_Should_Keep_Path_Params(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+
+			ns := time.Now().UnixNano()
+			tagged := make(chan interface{}, 2)
+			var version int32
+			r.GET("/swr_path/:id", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:swr_path:%d:%v", ns, params["id"])
+						}, Tags: func(params map[string]interface{}) []string {
+							tagged <- params["id"]
+							return []string{fmt.Sprintf("user:%d:%v", ns, params["id"])}
+						}, CacheTime: time.Millisecond * 100, StaleWhileRevalidate: time.Hour},
+					},
+				},
+				func(c *gin.Context) {
+					c.String(200, "v%d", atomic.AddInt32(&version, 1))
+				},
+			))
+			get := func() {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/swr_path/1", nil)
+				r.ServeHTTP(w, req)
+			}
+
+			get()
+			time.Sleep(time.Millisecond * 150)
+			get()
+			assert.Eventually(t, func() bool {
+				return cache.Load(context.Background(), fmt.Sprintf("anson:swr_path:%d:1", ns)) == "v2"
+			}, time.Second*2, time.Millisecond*20)
+
+			// the background refresh sees the params of the route as the request did
+			assert.Equal(t, "1", <-tagged)
+			assert.Equal(t, "1", <-tagged)
+		})
+	}
+}
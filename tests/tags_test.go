@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/pygzfei/gin-cache/internal"
+	"github.com/pygzfei/gin-cache/pkg/define"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Evict_Tags_Should_Remove_Tagged_Entries(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			r, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			cache.OnCacheError = func(ctx context.Context, op string, keys []string, err error) {
+				t.Errorf("%s %v: %v", op, keys, err)
+			}
+
+			ns := time.Now().UnixNano()
+			productTag := func(params map[string]interface{}) []string {
+				return []string{fmt.Sprintf("product:%d:%v", ns, params["id"])}
+			}
+			r.GET("/tagged/:tenant/products/:id", cache.Handler(
+				define.Caching{
+					Cacheable: []define.Cacheable{
+						{GenKey: func(params map[string]interface{}) string {
+							return fmt.Sprintf("anson:tagged:%d:%v:%v", ns, params["tenant"], params["id"])
+						}, Tags: productTag},
+					},
+				},
+				func(c *gin.Context) {
+					// the tenant is only known to the handler
+					define.AddCacheTags(c, fmt.Sprintf("tenant:%d:%s", ns, c.Param("tenant")))
+					c.String(200, "%s %s", c.Param("tenant"), c.Param("id"))
+				},
+			))
+			r.POST("/tagged/products/:id", cache.Handler(
+				define.Caching{
+					EvictRules: []define.CacheEvictRule{{EvictTags: productTag}},
+				},
+				func(c *gin.Context) {},
+			))
+			r.DELETE("/tagged/:tenant", cache.Handler(
+				define.Caching{
+					EvictRules: []define.CacheEvictRule{{EvictTags: func(params map[string]interface{}) []string {
+						return []string{fmt.Sprintf("tenant:%d:%v", ns, params["tenant"])}
+					}}},
+				},
+				func(c *gin.Context) {},
+			))
+
+			serve := func(method, url string) {
+				w := httptest.NewRecorder()
+				req, _ := http.NewRequest(method, url, nil)
+				r.ServeHTTP(w, req)
+			}
+			cached := func(tenant string, id int) bool {
+				return cache.Load(context.Background(), fmt.Sprintf("anson:tagged:%d:%s:%d", ns, tenant, id)) != ""
+			}
+
+			for _, url := range []string{"/tagged/a/products/1", "/tagged/a/products/2", "/tagged/b/products/1", "/tagged/b/products/3"} {
+				serve(http.MethodGet, url)
+			}
+			assert.True(t, cached("a", 1) && cached("a", 2) && cached("b", 1) && cached("b", 3))
+
+			serve(http.MethodPost, "/tagged/products/1")
+			assert.False(t, cached("a", 1) || cached("b", 1))
+			assert.True(t, cached("a", 2) && cached("b", 3))
+
+			serve(http.MethodDelete, "/tagged/a")
+			assert.False(t, cached("a", 2))
+			assert.True(t, cached("b", 3))
+		})
+	}
+}
+
+func Test_Tag_Index_Should_Forget_Expired_Keys(t *testing.T) {
+
+	for _, runFor := range []RunFor{MemoryCache, RedisCache} {
+
+		t.Run(fmt.Sprintf("run for %v", runFor), func(t *testing.T) {
+			_, cache := givingCacheOfHttpServer(time.Hour, runFor)
+			driver := cache.Cache.(internal.TagCache)
+			ctx := context.Background()
+
+			ns := time.Now().UnixNano()
+			tag := fmt.Sprintf("anson:index:%d", ns)
+			key := fmt.Sprintf("anson:index:%d:expired", ns)
+
+			assert.Nil(t, cache.Cache.Set(ctx, key, "v1", time.Millisecond*50))
+			assert.Nil(t, driver.Tag(ctx, key, []string{tag}, time.Millisecond*50))
+			time.Sleep(time.Millisecond * 100)
+
+			// stored again without the tag
+			assert.Nil(t, cache.Cache.Set(ctx, key, "v2", time.Hour))
+			assert.Nil(t, driver.EvictTags(ctx, []string{tag}))
+			assert.Equal(t, "v2", cache.Load(ctx, key))
+		})
+	}
+}
+
+func Test_Memory_Tag_Index_Should_Forget_Evicted_Keys(t *testing.T) {
+	_, cache := givingCacheOfHttpServer(time.Hour, MemoryCache)
+	driver := cache.Cache.(internal.TagCache)
+	ctx := context.Background()
+
+	assert.Nil(t, cache.Cache.Set(ctx, "anson:index:evicted", "v1", time.Hour))
+	assert.Nil(t, driver.Tag(ctx, "anson:index:evicted", []string{"anson:index"}, time.Hour))
+	assert.Nil(t, cache.Cache.DoEvict(ctx, []string{"anson:index:evicted"}))
+
+	// stored again without the tag
+	assert.Nil(t, cache.Cache.Set(ctx, "anson:index:evicted", "v2", time.Hour))
+	assert.Nil(t, driver.EvictTags(ctx, []string{"anson:index"}))
+	assert.Equal(t, "v2", cache.Load(ctx, "anson:index:evicted"))
+}
+
+func Test_Redis_Tag_Sets_Should_Be_Trimmed_Under_Key_Prefix(t *testing.T) {
+	_, cache := givingCacheOfHttpServer(time.Hour, RedisCache)
+	cache.KeyPrefix = fmt.Sprintf("anson%d", time.Now().UnixNano())
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer client.Close()
+	ctx := context.Background()
+
+	r := gin.New()
+	r.GET("/trimmed/:id", cache.Handler(
+		define.Caching{
+			Cacheable: []define.Cacheable{{
+				GenKey:    func(params map[string]interface{}) string { return fmt.Sprintf("anson:trimmed:%v", params["id"]) },
+				CacheTime: time.Millisecond * 50,
+				Tags:      func(params map[string]interface{}) []string { return []string{"trimmed"} },
+			}},
+		},
+		func(c *gin.Context) {
+			c.String(200, c.Param("id"))
+		},
+	))
+	get := func(id int) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/trimmed/%d", id), nil)
+		r.ServeHTTP(w, req)
+	}
+
+	set := cache.KeyPrefix + ":v0:tag:trimmed"
+	get(1)
+	get(2)
+	assert.Equal(t, int64(2), client.ZCard(ctx, set).Val())
+	time.Sleep(time.Millisecond * 100)
+	get(3)
+	assert.Equal(t, []string{cache.KeyPrefix + ":v0:anson:trimmed:3"}, client.ZRange(ctx, set, 0, -1).Val())
+	ttl := client.PTTL(ctx, set).Val()
+	assert.True(t, ttl > 0 && ttl <= time.Millisecond*50, ttl)
+}